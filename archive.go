@@ -0,0 +1,211 @@
+package migrator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewTarSource creates a MigrationSource that reads migration files from
+// migrationsDir and rollback files from rollbacksDir within a tar archive.
+// Gzip compressed archives are detected and decompressed automatically. The
+// archive is read in full before NewTarSource returns.
+func NewTarSource(r io.Reader, migrationsDir, rollbacksDir string) (MigrationSource, error) {
+	br := bufio.NewReader(r)
+
+	// Gzip streams always start with the same two magic bytes.
+	var archive io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, NewErrReadingArchive(err)
+		}
+		defer gz.Close()
+
+		archive = gz
+	}
+
+	files := memFS{}
+	tr := tar.NewReader(archive)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, NewErrReadingArchive(err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			files.addDir(header.Name)
+		case tar.TypeReg:
+			contents, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, NewErrReadingArchive(err)
+			}
+
+			files.addFile(header.Name, contents)
+		}
+	}
+
+	return NewFSSource(files, cleanArchivePath(migrationsDir), cleanArchivePath(rollbacksDir)), nil
+}
+
+// NewZipSource creates a MigrationSource that reads migration files from
+// migrationsDir and rollback files from rollbacksDir within a zip archive of
+// the given size. The archive is read in full before NewZipSource returns.
+func NewZipSource(r io.ReaderAt, size int64, migrationsDir, rollbacksDir string) (MigrationSource, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, NewErrReadingArchive(err)
+	}
+
+	files := memFS{}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			files.addDir(f.Name)
+			continue
+		}
+
+		contents, err := readZipFile(f)
+		if err != nil {
+			return nil, NewErrReadingArchive(err)
+		}
+
+		files.addFile(f.Name, contents)
+	}
+
+	return NewFSSource(files, cleanArchivePath(migrationsDir), cleanArchivePath(rollbacksDir)), nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// cleanArchivePath turns an archive entry name, such as ./migrations/up/, into
+// the form used by memFS.
+func cleanArchivePath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+// memFS is a read-only, in-memory fs.FS holding the contents of an archive.
+// Every directory containing a file is implied, so archives without
+// directory entries can still be listed.
+type memFS map[string]*memFile
+
+func (m memFS) addDir(name string) {
+	name = cleanArchivePath(name)
+
+	for name != "." {
+		if _, ok := m[name]; ok {
+			return
+		}
+
+		m[name] = &memFile{name: path.Base(name), dir: true}
+		name = path.Dir(name)
+	}
+}
+
+func (m memFS) addFile(name string, contents []byte) {
+	name = cleanArchivePath(name)
+	m.addDir(path.Dir(name))
+	m[name] = &memFile{name: path.Base(name), contents: contents}
+}
+
+func (m memFS) Open(name string) (fs.File, error) {
+	name = cleanArchivePath(name)
+
+	f, ok := m[name]
+	if !ok && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if !ok {
+		f = &memFile{name: ".", dir: true}
+	}
+
+	return &openMemFile{memFile: f, Reader: bytes.NewReader(f.contents)}, nil
+}
+
+func (m memFS) ReadFile(name string) ([]byte, error) {
+	f, ok := m[cleanArchivePath(name)]
+	if !ok || f.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return append([]byte(nil), f.contents...), nil
+}
+
+func (m memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	name = cleanArchivePath(name)
+
+	if f, ok := m[name]; (!ok && name != ".") || (ok && !f.dir) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var entries []fs.DirEntry
+	for p, f := range m {
+		if path.Dir(p) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(f))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// memFile is a file or directory within a memFS. It is its own fs.FileInfo.
+type memFile struct {
+	name     string
+	contents []byte
+	dir      bool
+}
+
+func (f *memFile) Name() string       { return f.name }
+func (f *memFile) Size() int64        { return int64(len(f.contents)) }
+func (f *memFile) ModTime() time.Time { return time.Time{} }
+func (f *memFile) IsDir() bool        { return f.dir }
+func (f *memFile) Sys() interface{}   { return nil }
+
+func (f *memFile) Mode() fs.FileMode {
+	if f.dir {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+type openMemFile struct {
+	*memFile
+	*bytes.Reader
+}
+
+func (f *openMemFile) Stat() (fs.FileInfo, error) { return f.memFile, nil }
+func (f *openMemFile) Close() error               { return nil }
@@ -0,0 +1,149 @@
+package migrator_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+var archiveFiles = map[string]string{
+	"./migrations/up/1_create-users_up.sql":     "CREATE TABLE users (id INT);",
+	"./migrations/down/1_create-users_down.sql": "DROP TABLE users;",
+	"./migrations/up/2_create-posts_up.sql":     "CREATE TABLE posts (id INT);",
+	"./migrations/down/2_create-posts_down.sql": "DROP TABLE posts;",
+}
+
+func tarArchive(t *testing.T, compress bool) *bytes.Buffer {
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+
+	tw := tar.NewWriter(&buf)
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+
+	for name, contents := range archiveFiles {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0600,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatalf("error writing tar header: %s", err)
+		}
+
+		tw.Write([]byte(contents))
+	}
+
+	tw.Close()
+	if gz != nil {
+		gz.Close()
+	}
+
+	return &buf
+}
+
+func assertArchiveMigrations(t *testing.T, source migrator.MigrationSource) {
+	ms, err := source.Migrations(mock.MockLogger())
+	if err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(ms))
+	}
+
+	for _, m := range ms {
+		if !strings.HasPrefix(string(m.FileContents), "CREATE TABLE") ||
+			!strings.HasPrefix(string(m.Rollback.FileContents), "DROP TABLE") {
+			t.Errorf("migration was not read from the archive correctly: %+v", m)
+		}
+	}
+}
+
+func TestTarSourceReadsMigrationsFromAnArchive(t *testing.T) {
+	source, err := migrator.NewTarSource(tarArchive(t, false), "migrations/up", "migrations/down")
+	if err != nil {
+		t.Fatalf("error reading archive: %s", err)
+	}
+
+	assertArchiveMigrations(t, source)
+}
+
+func TestTarSourceReadsMigrationsFromAGzippedArchive(t *testing.T) {
+	source, err := migrator.NewTarSource(tarArchive(t, true), "./migrations/up/", "migrations/down")
+	if err != nil {
+		t.Fatalf("error reading archive: %s", err)
+	}
+
+	assertArchiveMigrations(t, source)
+}
+
+func TestZipSourceReadsMigrationsFromAnArchive(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for name, contents := range archiveFiles {
+		w, err := zw.Create(strings.TrimPrefix(name, "./"))
+		if err != nil {
+			t.Fatalf("error writing zip entry: %s", err)
+		}
+
+		w.Write([]byte(contents))
+	}
+	zw.Close()
+
+	r := bytes.NewReader(buf.Bytes())
+	source, err := migrator.NewZipSource(r, r.Size(), "migrations/up", "migrations/down")
+	if err != nil {
+		t.Fatalf("error reading archive: %s", err)
+	}
+
+	assertArchiveMigrations(t, source)
+}
+
+func TestInvalidArchiveResultsInAnError(t *testing.T) {
+	r := strings.NewReader("not an archive")
+
+	_, err := migrator.NewZipSource(r, r.Size(), "migrations/up", "migrations/down")
+	if _, ok := err.(migrator.ErrReadingArchive); !ok {
+		t.Errorf("error returned was not correct: %v", err)
+	}
+}
+
+func TestConfiguredSourceDoesNotNeedDirectories(t *testing.T) {
+	source, err := migrator.NewTarSource(tarArchive(t, false), "migrations/up", "migrations/down")
+	if err != nil {
+		t.Fatalf("error reading archive: %s", err)
+	}
+
+	config := migrator.Configuration{
+		DatabaseConnectionString: "my-connection-string",
+		Source:                   source,
+	}
+
+	var ran []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		ran = append(ran, m.FileName)
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(ran) != 2 {
+		t.Errorf("migrations were not read from the configured source: %v", ran)
+	}
+}
@@ -0,0 +1,118 @@
+package migrator_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+func TestMigrationChecksumIsWrittenToHistoryTable(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var checksum string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.WriteMigrationHistoryFunc = func(m migrator.Migration, e migrator.Execution) error {
+		checksum = m.Checksum()
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Migrate()
+
+	expected := migrator.Migration{}.Checksum()
+	if checksum != expected {
+		t.Errorf("checksum was not correct: %s", checksum)
+	}
+}
+
+func TestChangedMigrationFilesResultInADriftError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	ioutil.WriteFile(fmt.Sprintf("%s/2_second-migration_up.sql", config.MigrationsDir), []byte("edited"), 0600)
+	ioutil.WriteFile(fmt.Sprintf("%s/2_second-migration_down.sql", config.RollbacksDir), []byte(""), 0600)
+
+	transactionCreated := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       1,
+				FileName: "1_first-migration_up.sql",
+				Checksum: migrator.Migration{}.Checksum(),
+			},
+			{
+				ID:       2,
+				FileName: "2_second-migration_up.sql",
+				Checksum: migrator.Migration{FileContents: []byte("original")}.Checksum(),
+			},
+		}, nil
+	}
+	db.BeginTransactionFunc = func() error {
+		transactionCreated = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	err := m.Migrate()
+	drift, ok := err.(migrator.ErrMigrationDrift)
+	if !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if len(drift.Files) != 1 || drift.Files[0] != "2_second-migration_up.sql" {
+		t.Errorf("drifted files were not correct: %v", drift.Files)
+	}
+	if transactionCreated {
+		t.Errorf("transaction was created when it shouldn't have been")
+	}
+}
+
+func TestMigrationsWithoutARecordedChecksumAreNotVerified(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       1,
+				FileName: "1_first-migration_up.sql",
+			},
+		}, nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Errorf("error returned when it shouldn't have been: %s", err)
+	}
+}
+
+func TestDriftIsIgnoredWhenAllowed(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.AllowDrift = true
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       1,
+				FileName: "1_first-migration_up.sql",
+				Checksum: "not-the-checksum",
+			},
+		}, nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Errorf("error returned when it shouldn't have been: %s", err)
+	}
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/bunsenapp/migrator"
 	"github.com/bunsenapp/migrator/mysql"
+	"github.com/bunsenapp/migrator/postgres"
+	"github.com/bunsenapp/migrator/sqlite"
 )
 
 const helpText = `
@@ -18,13 +27,29 @@ A super simple tool to run database migrations.
 
 Commands:
 	migrate  Run migrations that don't exist in the database
-	rollback Rollback a specific migration
+	rollback Rollback a specific migration, the latest -steps migrations or down -to an ID
+	status   List applied, pending and orphaned migrations
+	validate Check the migration files for duplicate IDs, gaps and rollbacks without a migration
 
 Options:
+	-allow-drift        Continue even if migration files have changed since they were ran, for emergencies only
 	-connection-string  The connection string of the database to run the migrations on (default is .)
+	-dry-run            Print the files and SQL that would run, in order, without changing the database
+	-format             The output format of the status command, table or json (default is table)
+	-gaps               How gaps between migration IDs are treated: error, warn or ignore (default is ignore)
+	-history-table      The table migrations are recorded in, optionally qualified by its schema, e.g. ops.migration_history (default is migration_history)
+	-lock-timeout       How long to wait for another run to release the migration lock, e.g. 30s (default is 1m)
+	-log-level          The least severe events to log: debug, info, warn or error (default is info)
 	-migration-dir      The directory where the UP migration scripts are stored (default is migrations/up)
+	-out-of-order       How migrations older than the latest ran migration are treated: allow, warn or reject (default is allow)
 	-rollback-dir       The directory where the DOWN migration scripts are stored (default is migrations/down)
-	-type               The type of database you are connecting to (MySQL) (default is mysql)
+	-source             Where to read the migration and rollback directories from: file://DIR, tar://ARCHIVE.tar(.gz) or zip://ARCHIVE.zip (default is the current directory)
+	-steps              The number of latest migrations to roll back in one transaction
+	-target             The ID of the migration to migrate up to; later migrations are left unapplied
+	-timeout            The maximum time the run may take before it is cancelled and rolled back, e.g. 5m (default is no timeout)
+	-to                 The ID of the migration to roll back to; every later migration is rolled back in one transaction
+	-transaction-mode   How migrations are wrapped in transactions: all, per-migration or none (default is per-migration for MySQL, all otherwise)
+	-type               The type of database you are connecting to (MySQL, PostgreSQL, SQLite) (default is mysql)
 `
 
 func main() {
@@ -38,67 +63,331 @@ func main() {
 	var migDir string
 	var rolDir string
 	var rollbackFile string
+	var timeout time.Duration
+	var lockTimeout time.Duration
+	var format string
+	var dryRun bool
+	var allowDrift bool
+	var steps int
+	var target int64
+	var migrateTarget int64
+	var transactionMode string
+	var source string
+	var gaps string
+	var outOfOrder string
+	var historyTable string
+	var logLevel string
 
-	migrateCommand := flag.NewFlagSet("migrate", flag.ExitOnError)
-	migrateCommand.StringVar(&dbType, "type", "mysql", "the type of database you're connecting to (MySQL, MsSQL, PostgreSQL)")
-	migrateCommand.StringVar(&conString, "connection-string", ".", "The connection string of the database to run the migrations on")
-	migrateCommand.StringVar(&migDir, "migration-dir", "migrations/up", "The directory where the migration scripts are stored.")
-	migrateCommand.StringVar(&rolDir, "rollback-dir", "migrations/down", "The directory where the rollback scripts are stored.")
+	// newCommand creates a flag set with the options shared by every command.
+	newCommand := func(name string) *flag.FlagSet {
+		c := flag.NewFlagSet(name, flag.ExitOnError)
+		c.StringVar(&dbType, "type", "mysql", "the type of database you're connecting to (MySQL, PostgreSQL, SQLite)")
+		c.StringVar(&conString, "connection-string", ".", "The connection string of the database to run the migrations on")
+		c.StringVar(&migDir, "migration-dir", "migrations/up", "The directory where the migration scripts are stored.")
+		c.StringVar(&rolDir, "rollback-dir", "migrations/down", "The directory where the rollback scripts are stored.")
+		c.StringVar(&source, "source", "", "Where the migration and rollback directories are read from (file://, tar:// or zip://).")
+		c.DurationVar(&timeout, "timeout", 0, "The maximum time the run may take before it is cancelled and rolled back.")
+		c.StringVar(&transactionMode, "transaction-mode", "", "How migrations are wrapped in transactions (all, per-migration, none).")
+		c.StringVar(&gaps, "gaps", "ignore", "How gaps between migration IDs are treated (error, warn, ignore).")
+		c.StringVar(&outOfOrder, "out-of-order", "allow", "How migrations older than the latest ran migration are treated (allow, warn, reject).")
+		c.StringVar(&historyTable, "history-table", migrator.DefaultHistoryTable, "The table migrations are recorded in, optionally qualified by its schema (schema.table).")
+		c.StringVar(&logLevel, "log-level", "info", "The least severe events to log (debug, info, warn, error).")
+		return c
+	}
+
+	migrateCommand := newCommand("migrate")
+	migrateCommand.DurationVar(&lockTimeout, "lock-timeout", migrator.DefaultLockTimeout, "How long to wait for another run to release the migration lock.")
+	migrateCommand.BoolVar(&dryRun, "dry-run", false, "Print the files and SQL that would run without changing the database.")
+	migrateCommand.BoolVar(&allowDrift, "allow-drift", false, "Continue even if migration files have changed since they were ran. For emergencies only.")
+
+	migrateCommand.Int64Var(&migrateTarget, "target", -1, "The ID of the migration to stop after; later migrations are left unapplied.")
+
+	rollbackCommand := newCommand("rollback")
+	rollbackCommand.DurationVar(&lockTimeout, "lock-timeout", migrator.DefaultLockTimeout, "How long to wait for another run to release the migration lock.")
+	rollbackCommand.BoolVar(&dryRun, "dry-run", false, "Print the files and SQL that would run without changing the database.")
+	rollbackCommand.BoolVar(&allowDrift, "allow-drift", false, "Continue even if migration files have changed since they were ran. For emergencies only.")
 
-	rollbackCommand := flag.NewFlagSet("rollback", flag.ExitOnError)
-	rollbackCommand.StringVar(&dbType, "type", "mysql", "the type of database you're connecting to (MySQL, MsSQL, PostgreSQL)")
-	rollbackCommand.StringVar(&conString, "connection-string", ".", "The connection string of the database to run the migrations on")
-	rollbackCommand.StringVar(&migDir, "migration-dir", "migrations/up", "The directory where the migration scripts are stored.")
-	rollbackCommand.StringVar(&rolDir, "rollback-dir", "migrations/down", "The directory where the rollback scripts are stored.")
+	rollbackCommand.IntVar(&steps, "steps", 0, "The number of latest migrations to roll back.")
+	rollbackCommand.Int64Var(&target, "to", -1, "The ID of the migration to roll back to; later migrations are rolled back.")
 
-	if len(os.Args) <= 2 {
-		fmt.Println(helpText)
+	statusCommand := newCommand("status")
+	statusCommand.StringVar(&format, "format", "table", "The output format of the status command (table, json).")
+
+	validateCommand := newCommand("validate")
+
+	if len(os.Args) < 2 {
+		fmt.Print(helpText)
 		return
 	}
 
-	// There are currently two commands: migrate and rollback.
+	// Status output is written to stdout so log lines must not be mixed in.
+	logOutput := os.Stdout
+
 	switch os.Args[1] {
 	case "migrate":
 		migrateCommand.Parse(os.Args[2:])
 	case "rollback":
 		rollbackCommand.Parse(os.Args[2:])
-		rollbackFile = os.Args[len(os.Args)-1]
+		rollbackFile = rollbackCommand.Arg(0)
+
+		given := 0
+		for _, set := range []bool{rollbackFile != "", steps > 0, target >= 0} {
+			if set {
+				given++
+			}
+		}
+
+		if given > 1 {
+			fmt.Fprintf(os.Stderr, "only one of a migration file name, -steps and -to may be given\n")
+			os.Exit(2)
+		}
+	case "status":
+		statusCommand.Parse(os.Args[2:])
+		logOutput = os.Stderr
+	case "validate":
+		validateCommand.Parse(os.Args[2:])
 	default:
-		fmt.Println(helpText)
+		fmt.Print(helpText)
 		return
 	}
 
+	var mode migrator.TransactionMode
+	switch strings.ToLower(transactionMode) {
+	case "all":
+		mode = migrator.TransactionModeAll
+	case "per-migration":
+		mode = migrator.TransactionModePerMigration
+	case "none":
+		mode = migrator.TransactionModeNone
+	case "":
+		mode = migrator.TransactionModeDefault
+	default:
+		fmt.Fprintf(os.Stderr, "unknown transaction mode: %s\n", transactionMode)
+		os.Exit(2)
+	}
+
+	var gapPolicy migrator.GapPolicy
+	switch strings.ToLower(gaps) {
+	case "error":
+		gapPolicy = migrator.GapPolicyError
+	case "warn":
+		gapPolicy = migrator.GapPolicyWarn
+	case "ignore":
+		gapPolicy = migrator.GapPolicyIgnore
+	default:
+		fmt.Fprintf(os.Stderr, "unknown gap policy: %s\n", gaps)
+		os.Exit(2)
+	}
+
+	var outOfOrderPolicy migrator.OutOfOrderPolicy
+	switch strings.ToLower(outOfOrder) {
+	case "allow":
+		outOfOrderPolicy = migrator.OutOfOrderAllow
+	case "warn":
+		outOfOrderPolicy = migrator.OutOfOrderWarn
+	case "reject":
+		outOfOrderPolicy = migrator.OutOfOrderReject
+	default:
+		fmt.Fprintf(os.Stderr, "unknown out of order policy: %s\n", outOfOrder)
+		os.Exit(2)
+	}
+
+	migrationSource, err := openSource(source, migDir, rolDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening migration source: %s\n", err)
+		os.Exit(2)
+	}
+
 	var db migrator.DatabaseServicer
-	var err error
 	config := migrator.Configuration{
 		DatabaseConnectionString: conString,
 		MigrationsDir:            migDir,
 		RollbacksDir:             rolDir,
+		Source:                   migrationSource,
+		LockTimeout:              lockTimeout,
+		DryRun:                   dryRun,
+		AllowDrift:               allowDrift,
+		TransactionMode:          mode,
+		GapPolicy:                gapPolicy,
+		OutOfOrderPolicy:         outOfOrderPolicy,
+		HistoryTable:             historyTable,
+	}
+
+	if i := strings.Index(historyTable, "."); i >= 0 {
+		config.HistorySchema = historyTable[:i]
+		config.HistoryTable = historyTable[i+1:]
+	}
+	logger := log.New(logOutput, "[Migrator] ", 1)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -log-level: %s\n", err)
+		os.Exit(2)
+	}
+
+	// Validating only reads the migration files, so it does not need a
+	// database and can run from a pre-commit hook.
+	if os.Args[1] == "validate" {
+		m, err := migrator.NewMigrator(config, nil, logger)
+		if err == nil {
+			m.Logger = migrator.NewStdLoggerWithLevel(logger, level)
+			err = m.Validate()
+		}
+
+		if err != nil {
+			logger.Printf("validation failed: %s\n", err)
+			os.Exit(1)
+		}
+
+		logger.Printf("migrations are valid\n")
+		return
 	}
-	logger := log.New(os.Stdout, "[Migrator] ", 1)
 
 	switch strings.ToLower(dbType) {
 	case "mysql":
 		db, err = mysql.NewMySQLDatabaseServicer(config.DatabaseConnectionString)
 		break
+	case "postgres", "postgresql":
+		db, err = postgres.NewPostgreSQLDatabaseServicer(config.DatabaseConnectionString)
+		break
+	case "sqlite", "sqlite3":
+		db, err = sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+		break
 	}
 	if err != nil {
-		panic("unable to initialise database servicer")
+		logger.Printf("unable to initialise database servicer: %s\n", err)
+		os.Exit(1)
 	}
 
 	m, err := migrator.NewMigrator(config, db, logger)
 	if err != nil {
-		panic(fmt.Sprintf("error creating migrator instance: %s\n", err))
+		logger.Printf("error creating migrator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	m.Logger = migrator.NewStdLoggerWithLevel(logger, level)
+
+	// Interrupting the process cancels the run, which rolls back the open
+	// transaction rather than leaving it dangling.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
 	switch os.Args[1] {
 	case "migrate":
-		err = m.Migrate()
+		if migrateTarget >= 0 {
+			err = m.MigrateToContext(ctx, migrateTarget)
+		} else {
+			err = m.MigrateContext(ctx)
+		}
 	case "rollback":
-		err = m.Rollback(rollbackFile)
+		switch {
+		case steps > 0:
+			err = m.RollbackStepsContext(ctx, steps)
+		case target >= 0:
+			err = m.RollbackToContext(ctx, target)
+		default:
+			err = m.RollbackContext(ctx, rollbackFile)
+		}
+	case "status":
+		var statuses []migrator.MigrationStatus
+		statuses, err = m.StatusContext(ctx)
+		if err == nil {
+			err = printStatus(os.Stdout, statuses, format)
+		}
 	}
 
 	if err != nil {
 		logger.Printf("error during migration run: %s\n", err)
+
+		// Exiting skips the deferred calls, so stop listening for
+		// interrupts first.
+		stop()
+		os.Exit(1)
+	}
+}
+
+// openSource creates the migration source named by the -source flag. The
+// migration and rollback directories are read from the current directory when
+// it is empty, or from within the given directory or archive otherwise.
+func openSource(source, migDir, rolDir string) (migrator.MigrationSource, error) {
+	switch {
+	case source == "":
+		return migrator.NewDirectorySource(migDir, rolDir), nil
+	case strings.HasPrefix(source, "file://"):
+		return migrator.NewFSSource(os.DirFS(strings.TrimPrefix(source, "file://")), migDir, rolDir), nil
+	case strings.HasPrefix(source, "tar://"):
+		f, err := os.Open(strings.TrimPrefix(source, "tar://"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return migrator.NewTarSource(f, migDir, rolDir)
+	case strings.HasPrefix(source, "zip://"):
+		f, err := os.Open(strings.TrimPrefix(source, "zip://"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		return migrator.NewZipSource(f, info.Size(), migDir, rolDir)
 	}
+
+	return nil, fmt.Errorf("unknown migration source: %s", source)
+}
+
+// printStatus writes the migration statuses to w as either an aligned table
+// or a JSON array.
+func printStatus(w io.Writer, statuses []migrator.MigrationStatus, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tMIGRATION\tSTATE\tRAN\tDURATION\tHOST\tUSER\tVERSION")
+		for _, s := range statuses {
+			ran := "-"
+			if s.Ran != nil {
+				ran = s.Ran.Format(time.RFC3339)
+			}
+			// Migrations recorded before executions were tracked have no
+			// version; their duration is unknown rather than zero.
+			duration := "-"
+			if s.Version != "" {
+				duration = s.Duration.String()
+			}
+			state := string(s.State)
+			if s.OutOfOrder {
+				state += " (out of order)"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.FileName, state, ran,
+				duration, orDash(s.Host), orDash(s.User), orDash(s.Version))
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown status format: %s", format)
+}
+
+// orDash returns s, or a dash when it is empty, for display in the status
+// table.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
 }
@@ -0,0 +1,70 @@
+package migrator
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// NoTransactionDirective is the header comment that opts a migration or
+// rollback file out of transactions. It must appear amongst the comments at
+// the top of the file, before the first statement.
+const NoTransactionDirective = "-- migrator:no-transaction"
+
+// hasNoTransactionDirective reports whether or not the header comments of a
+// SQL file contain the NoTransactionDirective.
+func hasNoTransactionDirective(contents []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") {
+			return false
+		}
+
+		if strings.EqualFold(line, NoTransactionDirective) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MigrateUpMarker and MigrateDownMarker separate the two halves of a
+// single-file migration such as 7_add-users.sql. Everything after the up marker
+// is the migration and everything after the down marker is its rollback.
+const (
+	MigrateUpMarker   = "-- +migrate Up"
+	MigrateDownMarker = "-- +migrate Down"
+)
+
+// splitSections splits the contents of a single-file migration into its up and
+// down sections. Anything before the first marker is discarded. hasUp and
+// hasDown report whether or not each marker was found.
+func splitSections(contents []byte) (up, down []byte, hasUp, hasDown bool) {
+	var current *[]byte
+
+	for _, line := range bytes.SplitAfter(contents, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+
+		switch {
+		case strings.EqualFold(trimmed, MigrateUpMarker):
+			current, hasUp = &up, true
+			continue
+		case strings.EqualFold(trimmed, MigrateDownMarker):
+			current, hasDown = &down, true
+			continue
+		}
+
+		if current != nil {
+			*current = append(*current, line...)
+		}
+	}
+
+	return up, down, hasUp, hasDown
+}
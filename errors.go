@@ -3,6 +3,7 @@ package migrator
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -39,6 +40,27 @@ var (
 	// ErrCommittingTransaction is an error that is raised when the application
 	// is, for some reason, unable to commit the transaction to the database.
 	ErrCommittingTransaction = errors.New("unable to commit database transaction")
+
+	// ErrMigrationLocked is an error that is raised when the migration lock
+	// is held by another process for longer than the configured lock timeout.
+	ErrMigrationLocked = errors.New("timed out waiting for migration lock held by another process")
+
+	// ErrInvalidRollbackSteps is an error that is raised when the number of
+	// migrations to roll back is not a positive number.
+	ErrInvalidRollbackSteps = errors.New("number of migrations to roll back must be positive")
+
+	// ErrTooManyRollbackSteps is an error that is raised when asked to roll
+	// back more migrations than have been ran.
+	ErrTooManyRollbackSteps = errors.New("cannot roll back more migrations than have been ran")
+
+	// ErrRollbackTargetNotRan is an error that is raised when the migration
+	// to roll back to has not been ran into the database.
+	ErrRollbackTargetNotRan = errors.New("rollback target migration has not been ran")
+
+	// ErrHistoryTableNotConfigurable is an error that is raised when a
+	// history table or schema is configured but the database servicer does
+	// not implement SetHistoryTable.
+	ErrHistoryTableNotConfigurable = errors.New("database servicer does not support configuring the history table")
 )
 
 // NewErrSearchingDir creates a new instance of the ErrSearchingDir struct.
@@ -100,6 +122,92 @@ func NewErrRunningRollback(r Rollback, err error) error {
 	}
 }
 
+// NewErrAcquiringLock creates a new instance of the ErrAcquiringLock struct.
+func NewErrAcquiringLock(err error) error {
+	return ErrAcquiringLock{
+		err: err,
+	}
+}
+
+// NewErrMigrationDrift creates a new instance of the ErrMigrationDrift struct.
+func NewErrMigrationDrift(files []string) error {
+	return ErrMigrationDrift{
+		Files: files,
+	}
+}
+
+// NewErrMigrationTargetNotFound creates a new instance of the
+// ErrMigrationTargetNotFound struct.
+func NewErrMigrationTargetNotFound(id int64) error {
+	return ErrMigrationTargetNotFound{
+		id: id,
+	}
+}
+
+// NewErrDuplicateMigrationID creates a new instance of the
+// ErrDuplicateMigrationID struct.
+func NewErrDuplicateMigrationID(id int64, files []string) error {
+	return ErrDuplicateMigrationID{
+		ID:    id,
+		Files: files,
+	}
+}
+
+// NewErrOrphanedRollbacks creates a new instance of the ErrOrphanedRollbacks
+// struct.
+func NewErrOrphanedRollbacks(files []string) error {
+	return ErrOrphanedRollbacks{
+		Files: files,
+	}
+}
+
+// NewErrMigrationGaps creates a new instance of the ErrMigrationGaps struct.
+func NewErrMigrationGaps(gaps []MigrationGap) error {
+	return ErrMigrationGaps{
+		Gaps: gaps,
+	}
+}
+
+// NewErrOutOfOrderMigrations creates a new instance of the
+// ErrOutOfOrderMigrations struct.
+func NewErrOutOfOrderMigrations(files []string) error {
+	return ErrOutOfOrderMigrations{
+		Files: files,
+	}
+}
+
+// NewErrHookFailed creates a new instance of the ErrHookFailed struct.
+func NewErrHookFailed(hook string, err error) error {
+	return ErrHookFailed{
+		hook: hook,
+		err:  err,
+	}
+}
+
+// NewErrInvalidHistoryTable creates a new instance of the
+// ErrInvalidHistoryTable struct.
+func NewErrInvalidHistoryTable(name string) error {
+	return ErrInvalidHistoryTable{
+		name: name,
+	}
+}
+
+// NewErrUnsupportedHistoryVersion creates a new instance of the
+// ErrUnsupportedHistoryVersion struct.
+func NewErrUnsupportedHistoryVersion(version, supported int) error {
+	return ErrUnsupportedHistoryVersion{
+		version:   version,
+		supported: supported,
+	}
+}
+
+// NewErrReadingArchive creates a new instance of the ErrReadingArchive struct.
+func NewErrReadingArchive(err error) error {
+	return ErrReadingArchive{
+		err: err,
+	}
+}
+
 // ErrSearchingDir is an error that is raised when the searching of a directory
 // fails.
 type ErrSearchingDir struct {
@@ -185,3 +293,147 @@ func (e ErrRunningRollback) Error() string {
 	return fmt.Sprintf("error whilst rolling back migration %s: %s",
 		e.r.FileName, e.err)
 }
+
+// ErrAcquiringLock is an error that is raised when the database fails whilst
+// the application is trying to take the migration lock.
+type ErrAcquiringLock struct {
+	err error
+}
+
+// Error yields the error string for the ErrAcquiringLock struct.
+func (e ErrAcquiringLock) Error() string {
+	return fmt.Sprintf("error acquiring migration lock: %s", e.err)
+}
+
+// ErrMigrationDrift is an error that is raised when migration files have been
+// changed since they were ran into the database.
+type ErrMigrationDrift struct {
+	// Files are the names of every migration file that has drifted.
+	Files []string
+}
+
+// Error yields the error string for the ErrMigrationDrift struct.
+func (e ErrMigrationDrift) Error() string {
+	return fmt.Sprintf("migration files have changed since they were ran: %s",
+		strings.Join(e.Files, ", "))
+}
+
+// ErrMigrationTargetNotFound is an error that is raised when the migration to
+// migrate up to does not exist among the migration files.
+type ErrMigrationTargetNotFound struct {
+	id int64
+}
+
+// Error yields the error string for the ErrMigrationTargetNotFound struct.
+func (e ErrMigrationTargetNotFound) Error() string {
+	return fmt.Sprintf("no migration file found with target id %d", e.id)
+}
+
+// ErrReadingArchive is an error that is raised when a tar or zip archive of
+// migrations cannot be read.
+type ErrReadingArchive struct {
+	err error
+}
+
+// Error yields the error string for the ErrReadingArchive struct.
+func (e ErrReadingArchive) Error() string {
+	return fmt.Sprintf("error reading migration archive: %s", e.err)
+}
+
+// ErrDuplicateMigrationID is an error that is raised when more than one
+// migration has the same ID.
+type ErrDuplicateMigrationID struct {
+	// ID is the ID shared by the migrations.
+	ID int64
+
+	// Files are the names of every migration with the ID.
+	Files []string
+}
+
+// Error yields the error string for the ErrDuplicateMigrationID struct.
+func (e ErrDuplicateMigrationID) Error() string {
+	return fmt.Sprintf("more than one migration has id %d: %s",
+		e.ID, strings.Join(e.Files, ", "))
+}
+
+// ErrOrphanedRollbacks is an error that is raised when rollback files exist
+// that do not belong to any migration.
+type ErrOrphanedRollbacks struct {
+	// Files are the names of every rollback file without a migration.
+	Files []string
+}
+
+// Error yields the error string for the ErrOrphanedRollbacks struct.
+func (e ErrOrphanedRollbacks) Error() string {
+	return fmt.Sprintf("rollback files have no matching migration: %s",
+		strings.Join(e.Files, ", "))
+}
+
+// ErrMigrationGaps is an error that is raised when migration IDs are missing
+// from the sequence and GapPolicyError is configured.
+type ErrMigrationGaps struct {
+	// Gaps are the ranges of missing migration IDs.
+	Gaps []MigrationGap
+}
+
+// Error yields the error string for the ErrMigrationGaps struct.
+func (e ErrMigrationGaps) Error() string {
+	gaps := make([]string, 0, len(e.Gaps))
+	for _, g := range e.Gaps {
+		gaps = append(gaps, g.String())
+	}
+
+	return fmt.Sprintf("migration ids are missing from the sequence: %s",
+		strings.Join(gaps, ", "))
+}
+
+// ErrOutOfOrderMigrations is an error that is raised when pending migrations
+// have an ID lower than the highest applied migration and OutOfOrderReject is
+// configured.
+type ErrOutOfOrderMigrations struct {
+	// Files are the names of every migration that is out of order.
+	Files []string
+}
+
+// Error yields the error string for the ErrOutOfOrderMigrations struct.
+func (e ErrOutOfOrderMigrations) Error() string {
+	return fmt.Sprintf("migrations are older than the latest ran migration: %s",
+		strings.Join(e.Files, ", "))
+}
+
+// ErrHookFailed is an error that is raised when a hook returns an error,
+// aborting the run.
+type ErrHookFailed struct {
+	hook string
+	err  error
+}
+
+// Error yields the error string for the ErrHookFailed struct.
+func (e ErrHookFailed) Error() string {
+	return fmt.Sprintf("%s hook failed: %s", e.hook, e.err)
+}
+
+// ErrInvalidHistoryTable is an error that is raised when the configured
+// history table or schema name is not a plain identifier.
+type ErrInvalidHistoryTable struct {
+	name string
+}
+
+// Error yields the error string for the ErrInvalidHistoryTable struct.
+func (e ErrInvalidHistoryTable) Error() string {
+	return fmt.Sprintf("invalid history table or schema name %q: only letters, digits and underscores are allowed", e.name)
+}
+
+// ErrUnsupportedHistoryVersion is an error that is raised when the history
+// table was created or upgraded by a newer release of migrator than the one
+// running.
+type ErrUnsupportedHistoryVersion struct {
+	version   int
+	supported int
+}
+
+// Error yields the error string for the ErrUnsupportedHistoryVersion struct.
+func (e ErrUnsupportedHistoryVersion) Error() string {
+	return fmt.Sprintf("history table is at version %d but this release of migrator only supports up to version %d",
+		e.version, e.supported)
+}
@@ -0,0 +1,79 @@
+package migrator
+
+import (
+	"os"
+	"os/user"
+	"runtime/debug"
+	"time"
+)
+
+// modulePath is the import path of this module, used to find its version in
+// the build information of the binary.
+const modulePath = "github.com/bunsenapp/migrator"
+
+// develVersion is the version recorded when migrator was not built from a
+// released module version.
+const develVersion = "(devel)"
+
+// Version is the version of migrator recorded against each migration that is
+// ran. It can be set at build time with
+// -ldflags "-X github.com/bunsenapp/migrator.Version=v1.2.3"; when it is empty
+// the version is read from the build information of the binary.
+var Version string
+
+// Execution describes how a migration was ran so that it can be recorded in
+// the migration history table.
+type Execution struct {
+	// Duration is how long the migration took to run.
+	Duration time.Duration
+
+	// Host is the name of the machine that ran the migration.
+	Host string
+
+	// User is the name of the operating system user that ran the migration.
+	User string
+
+	// Version is the version of migrator that ran the migration.
+	Version string
+}
+
+// executor returns an Execution describing the current machine, user and
+// migrator version, without a duration.
+func executor() Execution {
+	e := Execution{Version: version()}
+
+	if host, err := os.Hostname(); err == nil {
+		e.Host = host
+	}
+
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	} else {
+		e.User = os.Getenv("USER")
+	}
+
+	return e
+}
+
+// version resolves the version of migrator, preferring Version and falling
+// back to the module version the binary was built with. Builds outside of a
+// module, or from a local checkout, are reported as "(devel)".
+func version() string {
+	if Version != "" {
+		return Version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath && info.Main.Version != "" {
+			return info.Main.Version
+		}
+
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath && dep.Version != "" {
+				return dep.Version
+			}
+		}
+	}
+
+	return develVersion
+}
@@ -0,0 +1,10 @@
+package migrator
+
+// ResetRegistry removes every migration added with Register so tests do not
+// leak registrations into each other.
+func ResetRegistry() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = nil
+}
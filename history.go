@@ -0,0 +1,60 @@
+package migrator
+
+import "regexp"
+
+// DefaultHistoryTable is the name of the table migrations are recorded in
+// when Configuration.HistoryTable is not set.
+const DefaultHistoryTable = "migration_history"
+
+// identifierPattern matches the names allowed for the history table and its
+// schema. They are interpolated into SQL, so only plain identifiers are
+// accepted.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// historyTableConfigurer is implemented by database servicers that can record
+// migrations in a table other than DefaultHistoryTable in the current schema.
+type historyTableConfigurer interface {
+	// SetHistoryTable sets the schema and name of the migration history
+	// table. An empty schema is the connection's current schema. It returns
+	// an error if the servicer cannot use the given schema.
+	SetHistoryTable(schema, table string) error
+}
+
+// historyTable resolves the configured history table name, falling back to
+// DefaultHistoryTable.
+func (c Configuration) historyTable() string {
+	if c.HistoryTable == "" {
+		return DefaultHistoryTable
+	}
+
+	return c.HistoryTable
+}
+
+// validateHistoryTable checks the configured history table and schema names
+// are plain identifiers.
+func (c Configuration) validateHistoryTable() error {
+	if !identifierPattern.MatchString(c.historyTable()) {
+		return NewErrInvalidHistoryTable(c.HistoryTable)
+	}
+
+	if c.HistorySchema != "" && !identifierPattern.MatchString(c.HistorySchema) {
+		return NewErrInvalidHistoryTable(c.HistorySchema)
+	}
+
+	return nil
+}
+
+// configureHistoryTable tells the database servicer which history table to
+// use. Servicers that cannot be configured may only be used with the default
+// table.
+func (m Migrator) configureHistoryTable() error {
+	if c, ok := m.DatabaseServicer.(historyTableConfigurer); ok {
+		return c.SetHistoryTable(m.Config.HistorySchema, m.Config.historyTable())
+	}
+
+	if m.Config.HistorySchema != "" || m.Config.historyTable() != DefaultHistoryTable {
+		return ErrHistoryTableNotConfigurable
+	}
+
+	return nil
+}
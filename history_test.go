@@ -0,0 +1,108 @@
+package migrator_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+// configurableServicer is a mock database servicer that records the history
+// table it is told to use, or rejects it with err.
+type configurableServicer struct {
+	mock.MockDatabaseServicer
+
+	schema string
+	table  string
+	err    error
+}
+
+func (c *configurableServicer) SetHistoryTable(schema, table string) error {
+	c.schema = schema
+	c.table = table
+
+	return c.err
+}
+
+func TestInvalidHistoryTableNamesResultInAnError(t *testing.T) {
+	names := []struct{ schema, table string }{
+		{"", "migration history"},
+		{"", "history; DROP TABLE users"},
+		{"", "1history"},
+		{"ops-schema", "migration_history"},
+		{"ops.", "migration_history"},
+	}
+
+	for _, n := range names {
+		config := mock.ValidConfiguration()
+		config.HistorySchema = n.schema
+		config.HistoryTable = n.table
+
+		if _, ok := config.Validate().(migrator.ErrInvalidHistoryTable); !ok {
+			t.Errorf("expected schema %q and table %q to be invalid", n.schema, n.table)
+		}
+	}
+}
+
+func TestHistoryTableIsPassedToTheDatabaseServicer(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.HistorySchema = "ops"
+	config.HistoryTable = "billing_migrations"
+
+	db := &configurableServicer{MockDatabaseServicer: mock.WorkingMockDatabaseServicer()}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	if db.schema != "ops" || db.table != "billing_migrations" {
+		t.Errorf("expected ops.billing_migrations, got %s.%s", db.schema, db.table)
+	}
+}
+
+func TestDefaultHistoryTableIsPassedToTheDatabaseServicer(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	db := &configurableServicer{MockDatabaseServicer: mock.WorkingMockDatabaseServicer()}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if _, err := m.Status(); err != nil {
+		t.Fatalf("error retrieving status: %s", err)
+	}
+
+	if db.schema != "" || db.table != migrator.DefaultHistoryTable {
+		t.Errorf("expected the default history table, got %q.%q", db.schema, db.table)
+	}
+}
+
+func TestCustomHistoryTableWithAnUnconfigurableServicerResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.HistoryTable = "billing_migrations"
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), mock.MockLogServicer())
+	if err := m.Migrate(); err != migrator.ErrHistoryTableNotConfigurable {
+		t.Errorf("expected ErrHistoryTableNotConfigurable, got %v", err)
+	}
+}
+
+func TestHistoryTableRejectedByTheServicerResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.HistorySchema = "ops"
+
+	rejected := errors.New("schema is not supported")
+	db := &configurableServicer{MockDatabaseServicer: mock.WorkingMockDatabaseServicer(), err: rejected}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != rejected {
+		t.Errorf("expected the servicer's error, got %v", err)
+	}
+}
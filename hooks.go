@@ -0,0 +1,86 @@
+package migrator
+
+import "time"
+
+// Hooks are callbacks invoked around a migrate or rollback run, such as to
+// page an on-call channel, flush caches or record timings. Any of them may be
+// left nil. Hooks that return an error abort the run; when that happens before
+// a transaction is committed, the transaction is rolled back.
+type Hooks struct {
+	// BeforeRun is called with the migrations about to be migrated or rolled
+	// back, in order, before the first of them runs.
+	BeforeRun func(ms []Migration) error
+
+	// AfterMigration is called after each migration has ran, with how long
+	// it took and the error it failed with, if any. It is called before the
+	// migration's transaction is committed.
+	AfterMigration func(m Migration, d time.Duration, err error) error
+
+	// BeforeRollback is called before each migration is rolled back.
+	BeforeRollback func(m Migration) error
+
+	// AfterRollback is called after each migration has been rolled back,
+	// with how long it took and the error it failed with, if any. It is
+	// called before the rollback's transaction is committed.
+	AfterRollback func(m Migration, d time.Duration, err error) error
+
+	// AfterCommit is called with the migrations, or rollbacks, whose changes
+	// have just been committed. Migrations ran outside of a transaction are
+	// reported as soon as they have succeeded.
+	AfterCommit func(ms []Migration)
+
+	// OnFailure is called with the error a run fails with.
+	OnFailure func(err error)
+}
+
+func (h Hooks) beforeRun(ms []Migration) error {
+	if h.BeforeRun == nil {
+		return nil
+	}
+
+	return hookError("BeforeRun", h.BeforeRun(ms))
+}
+
+func (h Hooks) afterMigration(m Migration, d time.Duration, err error) error {
+	if h.AfterMigration == nil {
+		return nil
+	}
+
+	return hookError("AfterMigration", h.AfterMigration(m, d, err))
+}
+
+func (h Hooks) beforeRollback(m Migration) error {
+	if h.BeforeRollback == nil {
+		return nil
+	}
+
+	return hookError("BeforeRollback", h.BeforeRollback(m))
+}
+
+func (h Hooks) afterRollback(m Migration, d time.Duration, err error) error {
+	if h.AfterRollback == nil {
+		return nil
+	}
+
+	return hookError("AfterRollback", h.AfterRollback(m, d, err))
+}
+
+func (h Hooks) afterCommit(ms []Migration) {
+	if h.AfterCommit != nil {
+		h.AfterCommit(ms)
+	}
+}
+
+func (h Hooks) onFailure(err error) {
+	if h.OnFailure != nil {
+		h.OnFailure(err)
+	}
+}
+
+func hookError(hook string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return NewErrHookFailed(hook, err)
+}
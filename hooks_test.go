@@ -0,0 +1,162 @@
+package migrator_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+func TestHooksAreCalledAroundAMigrationRun(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var events []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		events = append(events, "run")
+		return nil
+	}
+	db.CommitTransactionFunc = func() error {
+		events = append(events, "commit")
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Hooks = migrator.Hooks{
+		BeforeRun: func(ms []migrator.Migration) error {
+			events = append(events, fmt.Sprintf("before run %d", len(ms)))
+			return nil
+		},
+		AfterMigration: func(mi migrator.Migration, d time.Duration, err error) error {
+			events = append(events, fmt.Sprintf("after %s %v", mi.FileName, err))
+			return nil
+		},
+		AfterCommit: func(ms []migrator.Migration) {
+			events = append(events, fmt.Sprintf("after commit %d", len(ms)))
+		},
+		OnFailure: func(err error) {
+			events = append(events, "failure")
+		},
+	}
+
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	expected := "[before run 1 run after 1_first-migration_up.sql <nil> commit after commit 1]"
+	if fmt.Sprint(events) != expected {
+		t.Errorf("hooks were not called in order: %v", events)
+	}
+}
+
+func TestAfterMigrationHookErrorAbortsBeforeCommit(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var committed, rolledBack bool
+	var failure error
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.CommitTransactionFunc = func() error {
+		committed = true
+		return nil
+	}
+	db.RollbackTransactionFunc = func() error {
+		rolledBack = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Hooks = migrator.Hooks{
+		AfterMigration: func(mi migrator.Migration, d time.Duration, err error) error {
+			return errors.New("cache flush failed")
+		},
+		OnFailure: func(err error) {
+			failure = err
+		},
+	}
+
+	err := m.Migrate()
+	if _, ok := err.(migrator.ErrHookFailed); !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if committed || !rolledBack {
+		t.Errorf("transaction was committed after a hook failed")
+	}
+
+	if failure != err {
+		t.Errorf("OnFailure was not called with the error: %v", failure)
+	}
+}
+
+func TestBeforeRunHookErrorStopsMigrationsRunning(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var ran bool
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		ran = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Hooks.BeforeRun = func(ms []migrator.Migration) error {
+		return errors.New("change freeze")
+	}
+
+	if _, ok := m.Migrate().(migrator.ErrHookFailed); !ok {
+		t.Errorf("hook error was not returned")
+	}
+
+	if ran {
+		t.Errorf("migrations were ran after BeforeRun failed")
+	}
+}
+
+func TestRollbackHooksAreCalledForEachRollback(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	os.Create(fmt.Sprintf("%s/2_migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/2_migration_down.sql", config.RollbacksDir))
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{ID: 1, FileName: "1_first-migration_up.sql"},
+			{ID: 2, FileName: "2_migration_up.sql"},
+		}, nil
+	}
+
+	var events []string
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Hooks = migrator.Hooks{
+		BeforeRollback: func(mi migrator.Migration) error {
+			events = append(events, fmt.Sprintf("before %d", mi.ID))
+			return nil
+		},
+		AfterRollback: func(mi migrator.Migration, d time.Duration, err error) error {
+			events = append(events, fmt.Sprintf("after %d", mi.ID))
+			return nil
+		},
+	}
+
+	if err := m.RollbackSteps(2); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	expected := "[before 2 after 2 before 1 after 1]"
+	if fmt.Sprint(events) != expected {
+		t.Errorf("rollback hooks were not called in order: %v", events)
+	}
+}
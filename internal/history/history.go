@@ -0,0 +1,310 @@
+// Package history creates, upgrades and reads the migration history table the
+// same way for each of the bundled database servicers. It is internal so that
+// the table layout can change without breaking the public API.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+)
+
+// Version is the version of the history table layout created by this
+// release. It is recorded alongside the history table, and tables recorded
+// with an earlier version, or with none at all, are upgraded in place. Each
+// version adds the following to the one before it:
+//
+//  1. the checksum, duration_ms, host, username and migrator_version columns
+//  2. a 64 bit id column
+//  3. a primary key on id
+//  4. an index on file_name
+//  5. sub-second precision for ran
+//
+// Where a database already meets a version, such as SQLite's 64 bit
+// integers, its upgrade does nothing.
+const Version = 5
+
+// column is a column added to the history table after it was first released,
+// when it held only id, file_name and ran.
+type column struct {
+	name string
+
+	// size is the maximum length of a text column, or 0 for a 64 bit
+	// integer column.
+	size int
+}
+
+// addedColumns are the columns added to the history table after it was first
+// released, in the order they were added. They are nullable so that rows
+// recorded before them remain valid.
+var addedColumns = []column{
+	{name: "checksum", size: 64},
+	{name: "duration_ms"},
+	{name: "host", size: 255},
+	{name: "username", size: 255},
+	{name: "migrator_version", size: 64},
+}
+
+// Columns returns the name of every column of the history table, in order.
+func Columns() []string {
+	names := []string{"id", "file_name", "ran"}
+	for _, c := range addedColumns {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+// Upgrade brings a history table up to a layout version. It must check the
+// table first and do nothing if the change has already been made, so that it
+// is safe to run again after an interrupted upgrade.
+type Upgrade func(ctx context.Context) error
+
+// Table describes a database servicer's history table so that its creation
+// and upgrades, and the version table recording its layout, are handled the
+// same way for every database. Servicers only supply the statements that
+// differ between databases.
+type Table struct {
+	// DB is the database holding the history table.
+	DB *sql.DB
+
+	// Name is the unquoted name of the history table.
+	Name string
+
+	// Qualify quotes a table name and prefixes it with the history table's
+	// schema, if there is one.
+	Qualify func(table string) string
+
+	// TableExists reports whether a table, given by its unquoted name,
+	// exists in the history table's schema.
+	TableExists func(ctx context.Context, table string) (bool, error)
+
+	// ColumnExists reports whether the history table has a column.
+	ColumnExists func(ctx context.Context, column string) (bool, error)
+
+	// IntegerType is the type of 64 bit integer columns. Defaults to BIGINT.
+	IntegerType string
+
+	// Create creates the history table with the layout of Version.
+	Create func(ctx context.Context) error
+
+	// Upgrades are the changes needed to bring the table up to each layout
+	// version, keyed by that version. The columns of version 1 are added
+	// for every database; versions a database already meets have no entry.
+	Upgrades map[int]Upgrade
+}
+
+// TryCreate creates the history table if it does not exist, or otherwise
+// upgrades it in place to Version. The boolean return value indicates whether
+// or not the table had to be created.
+func (t Table) TryCreate(ctx context.Context) (bool, error) {
+	exists, err := t.TableExists(ctx, t.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		return false, t.upgrade(ctx)
+	}
+
+	if err = t.Create(ctx); err != nil {
+		return false, err
+	}
+
+	return true, t.setVersion(ctx)
+}
+
+// upgrade runs the upgrades after the version recorded for the table. Tables
+// created before versions were recorded are version 0.
+func (t Table) upgrade(ctx context.Context) error {
+	version, err := t.version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > Version {
+		return migrator.NewErrUnsupportedHistoryVersion(version, Version)
+	}
+
+	if version == Version {
+		return nil
+	}
+
+	for v := version + 1; v <= Version; v++ {
+		if v == 1 {
+			err = t.addMissingColumns(ctx)
+		} else if upgrade, ok := t.Upgrades[v]; ok {
+			err = upgrade(ctx)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.setVersion(ctx)
+}
+
+// QueryFunc runs a query on either the database or the open transaction.
+type QueryFunc func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+
+// RanMigrations reads every migration recorded in the history table with
+// query. Tables created by older releases are read as they are, as a dry run
+// does not upgrade them.
+func (t Table) RanMigrations(ctx context.Context, query QueryFunc) ([]migrator.RanMigration, error) {
+	columns, err := t.selectColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := query(ctx, fmt.Sprintf("SELECT %s FROM %s", columns, t.Qualify(t.Name)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranMigrations []migrator.RanMigration
+
+	for rows.Next() {
+		var rm migrator.RanMigration
+		var checksum, host, username, version sql.NullString
+		var durationMs sql.NullInt64
+
+		err = rows.Scan(&rm.ID, &rm.FileName, &rm.Ran, &checksum, &durationMs, &host, &username, &version)
+		if err != nil {
+			return nil, err
+		}
+
+		rm.Checksum = checksum.String
+		rm.Duration = time.Duration(durationMs.Int64) * time.Millisecond
+		rm.Host = host.String
+		rm.User = username.String
+		rm.Version = version.String
+
+		ranMigrations = append(ranMigrations, rm)
+	}
+
+	return ranMigrations, rows.Err()
+}
+
+// selectColumns returns the columns to select when reading the history
+// table, in the order of Columns. Columns a table created by an older release
+// does not have yet are selected as NULL, so the table can be read before it
+// is upgraded, such as during a dry run.
+func (t Table) selectColumns(ctx context.Context) (string, error) {
+	version, err := t.version(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	columns := []string{"id", "file_name", "ran"}
+
+	for _, c := range addedColumns {
+		// Every column exists from version 1 onwards.
+		exists := version >= 1
+		if !exists {
+			if exists, err = t.ColumnExists(ctx, c.name); err != nil {
+				return "", err
+			}
+		}
+
+		if exists {
+			columns = append(columns, c.name)
+		} else {
+			columns = append(columns, "NULL AS "+c.name)
+		}
+	}
+
+	return strings.Join(columns, ", "), nil
+}
+
+// addMissingColumns adds any of addedColumns the table does not have.
+func (t Table) addMissingColumns(ctx context.Context) error {
+	for _, c := range addedColumns {
+		exists, err := t.ColumnExists(ctx, c.name)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		_, err = t.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+			t.Qualify(t.Name), c.name, t.definition(c)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// definition returns the type of a column as it is added to the table.
+func (t Table) definition(c column) string {
+	if c.size > 0 {
+		return fmt.Sprintf("VARCHAR(%d) NULL", c.size)
+	}
+
+	if t.IntegerType != "" {
+		return t.IntegerType + " NULL"
+	}
+
+	return "BIGINT NULL"
+}
+
+// versionTable is the unquoted name of the table recording the layout
+// version of the history table.
+func (t Table) versionTable() string {
+	return t.Name + "_version"
+}
+
+// version returns the layout version recorded for the history table, or 0
+// when it was created before versions were recorded.
+func (t Table) version(ctx context.Context) (int, error) {
+	exists, err := t.TableExists(ctx, t.versionTable())
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err = t.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM "+t.Qualify(t.versionTable())).Scan(&version)
+
+	return version, err
+}
+
+// setVersion records Version as the layout of the history table.
+func (t Table) setVersion(ctx context.Context) error {
+	table := t.Qualify(t.versionTable())
+
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + table + " (version INTEGER NOT NULL)",
+		"DELETE FROM " + table,
+		fmt.Sprintf("INSERT INTO %s (version) VALUES (%d)", table, Version),
+	}
+
+	for _, statement := range statements {
+		if _, err := t.DB.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QualifiedName quotes a table name with a database's identifier quote
+// character, doubling any it contains, and prefixes it with the quoted schema
+// if there is one.
+func QualifiedName(quote, schema, table string) string {
+	name := quote + strings.Replace(table, quote, quote+quote, -1) + quote
+	if schema == "" {
+		return name
+	}
+
+	return QualifiedName(quote, "", schema) + "." + name
+}
@@ -0,0 +1,51 @@
+// Package sqlutil holds the database/sql handling shared by the bundled
+// database servicers.
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/bunsenapp/migrator"
+)
+
+// RunFunc runs a migration written in Go inside tx, or in a transaction of
+// its own on db when tx is nil.
+func RunFunc(ctx context.Context, db *sql.DB, tx *sql.Tx, f migrator.MigrationFunc) error {
+	if tx != nil {
+		return f(tx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseSessionLock releases a lock held by the session of conn by running
+// query, then returns conn to the pool. If the lock cannot be released the
+// connection is discarded instead, so that the session, and therefore the
+// lock, does not linger in the pool.
+func ReleaseSessionLock(conn *sql.Conn, query string, args ...interface{}) error {
+	_, err := conn.ExecContext(context.Background(), query, args...)
+	if err != nil {
+		conn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+
+	closeErr := conn.Close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
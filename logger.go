@@ -0,0 +1,172 @@
+package migrator
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The keys used for the fields of every event logged by the Migrator, so that
+// log pipelines can rely on them.
+const (
+	// LogKeyMigrationID is the ID of the migration an event relates to.
+	LogKeyMigrationID = "migration_id"
+
+	// LogKeyFileName is the file name of the migration, or rollback, an
+	// event relates to.
+	LogKeyFileName = "file_name"
+
+	// LogKeyDuration is how long the step an event reports on took.
+	LogKeyDuration = "duration"
+
+	// LogKeyPhase is the stage of the run an event happened in, such as
+	// bootstrap, migrate or rollback.
+	LogKeyPhase = "phase"
+
+	// LogKeyError is the error that caused an event.
+	LogKeyError = "error"
+)
+
+// The phases of a run reported under LogKeyPhase.
+const (
+	phaseBootstrap = "bootstrap"
+	phaseMigrate   = "migrate"
+	phaseRollback  = "rollback"
+	phasePlan      = "plan"
+	phaseDiscover  = "discover"
+)
+
+// Logger is a leveled logger that takes a message followed by alternating
+// keys and values, such as Info("migrated", LogKeyFileName, "1_a_up.sql").
+// A *slog.Logger satisfies it as is.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NewSlogLogger adapts a *slog.Logger, falling back to slog.Default when l
+// is nil.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return l
+}
+
+// NewStdLogger adapts a *log.Logger, or any other LogServicer, by writing
+// each event as a single line of the level, message and key=value fields.
+// Every level is written; see NewStdLoggerWithLevel.
+func NewStdLogger(l LogServicer) Logger {
+	return NewStdLoggerWithLevel(l, slog.LevelDebug)
+}
+
+// NewStdLoggerWithLevel adapts a LogServicer as NewStdLogger does, discarding
+// events below level.
+func NewStdLoggerWithLevel(l LogServicer, level slog.Level) Logger {
+	return stdLogger{l: l, level: level}
+}
+
+type stdLogger struct {
+	l     LogServicer
+	level slog.Level
+}
+
+func (s stdLogger) Debug(msg string, keyvals ...interface{}) { s.log(slog.LevelDebug, msg, keyvals) }
+func (s stdLogger) Info(msg string, keyvals ...interface{})  { s.log(slog.LevelInfo, msg, keyvals) }
+func (s stdLogger) Warn(msg string, keyvals ...interface{})  { s.log(slog.LevelWarn, msg, keyvals) }
+func (s stdLogger) Error(msg string, keyvals ...interface{}) { s.log(slog.LevelError, msg, keyvals) }
+
+func (s stdLogger) log(level slog.Level, msg string, keyvals []interface{}) {
+	if level < s.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteString(" ")
+	b.WriteString(msg)
+
+	var texts []logText
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		if text, ok := value.(logText); ok {
+			texts = append(texts, text)
+			continue
+		}
+
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], formatLogValue(value))
+	}
+
+	// Text meant to be read, such as SQL, follows the line as it is.
+	for _, text := range texts {
+		b.WriteString("\n")
+		b.WriteString(strings.TrimRight(string(text), "\n"))
+	}
+
+	s.l.Printf("%s", b.String())
+}
+
+// logText is a field value, such as the SQL of a migration, that is meant to
+// be read as it is. The standard logger writes it verbatim below the event
+// rather than quoting it onto one line; slog receives it as a plain string.
+type logText string
+
+// LogValue yields the text as a plain string for slog.
+func (t logText) LogValue() slog.Value {
+	return slog.StringValue(string(t))
+}
+
+// formatLogValue quotes values that would otherwise be ambiguous on a single
+// line, such as SQL containing spaces or newlines.
+func formatLogValue(v interface{}) string {
+	var s string
+	switch value := v.(type) {
+	case time.Duration:
+		s = value.String()
+	case error:
+		s = value.Error()
+	case []byte:
+		s = string(value)
+	default:
+		s = fmt.Sprint(value)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// nopLogger discards every event. It is used when a Migrator has neither a
+// Logger nor a LogServicer.
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (nopLogger) Info(msg string, keyvals ...interface{})  {}
+func (nopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (nopLogger) Error(msg string, keyvals ...interface{}) {}
+
+// logger returns the Logger events are written to, adapting the LogServicer
+// when no Logger has been set.
+func (m Migrator) logger() Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+
+	if m.LogServicer != nil {
+		return NewStdLogger(m.LogServicer)
+	}
+
+	return nopLogger{}
+}
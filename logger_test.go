@@ -0,0 +1,170 @@
+package migrator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+func TestStdLoggerWritesLevelMessageAndFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := migrator.NewStdLogger(log.New(&buf, "", 0))
+	l.Warn("migration failed",
+		migrator.LogKeyFileName, "1_first-migration_up.sql",
+		migrator.LogKeyError, errors.New("syntax error"))
+
+	expected := `WARN migration failed file_name=1_first-migration_up.sql error="syntax error"` + "\n"
+	if buf.String() != expected {
+		t.Errorf("log line was not correct: %q", buf.String())
+	}
+}
+
+func TestStdLoggerDiscardsEventsBelowItsLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := migrator.NewStdLoggerWithLevel(log.New(&buf, "", 0), slog.LevelInfo)
+	l.Debug("database transaction created")
+	l.Info("migrated", migrator.LogKeyMigrationID, 1)
+
+	if buf.String() != "INFO migrated migration_id=1\n" {
+		t.Errorf("log output was not correct: %q", buf.String())
+	}
+}
+
+func TestStdLoggerWritesDryRunSQLVerbatim(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.DryRun = true
+
+	sql := "CREATE TABLE users (\n\tid INT\n);\n"
+	ioutil.WriteFile(fmt.Sprintf("%s/1_first-migration_up.sql", config.MigrationsDir), []byte(sql), 0600)
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.HistoryTableExistsFunc = func() (bool, error) {
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(config, db, log.New(&buf, "", 0))
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	expected := "INFO dry run: would migrate phase=plan migration_id=1 file_name=1_first-migration_up.sql\n" + sql
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("SQL was not written as it is: %s", buf.String())
+	}
+}
+
+func TestMigrationEventsAreLoggedWithStructuredFields(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), mock.MockLogServicer())
+	m.Logger = migrator.NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	var migrated map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("log line was not valid JSON: %s", line)
+		}
+
+		if event["msg"] == "migrated" {
+			migrated = event
+		}
+	}
+
+	if migrated == nil {
+		t.Fatalf("migration was not logged: %s", buf.String())
+	}
+
+	if migrated[migrator.LogKeyPhase] != "migrate" ||
+		migrated[migrator.LogKeyMigrationID] != float64(1) ||
+		migrated[migrator.LogKeyFileName] != "1_first-migration_up.sql" ||
+		migrated[migrator.LogKeyDuration] == nil {
+		t.Errorf("migration was not logged with the expected fields: %v", migrated)
+	}
+}
+
+func TestDryRunLogsTheSQLAsText(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.DryRun = true
+
+	sql := "CREATE TABLE users (id INT);"
+	ioutil.WriteFile(fmt.Sprintf("%s/1_first-migration_up.sql", config.MigrationsDir), []byte(sql), 0600)
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.HistoryTableExistsFunc = func() (bool, error) {
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Logger = migrator.NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	var planned map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("log line was not valid JSON: %s", line)
+		}
+
+		if event["msg"] == "dry run: would migrate" {
+			planned = event
+		}
+	}
+
+	if planned == nil {
+		t.Fatalf("planned migration was not logged: %s", buf.String())
+	}
+
+	if planned["sql"] != sql {
+		t.Errorf("expected the SQL to be logged as text, got %v", planned["sql"])
+	}
+}
+
+func TestFailedMigrationIsLoggedWithTheError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		return errors.New("syntax error")
+	}
+
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(config, db, log.New(&buf, "", 0))
+	m.Migrate()
+
+	if !strings.Contains(buf.String(), `ERROR migration failed phase=migrate migration_id=1 file_name=1_first-migration_up.sql`) ||
+		!strings.Contains(buf.String(), `error="syntax error"`) {
+		t.Errorf("failed migration was not logged: %s", buf.String())
+	}
+}
@@ -1,12 +1,11 @@
 package migrator
 
 import (
-	"fmt"
-	"io/ioutil"
-	"os"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
 	"sort"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -18,11 +17,15 @@ const (
 	PostgreSQLDatabaseType
 )
 
+// DefaultLockTimeout is how long the migrator waits for the migration lock
+// when Configuration.LockTimeout is not set.
+const DefaultLockTimeout = time.Minute
+
 // Migration is a representation of a migration that needs to run.
 type Migration struct {
 	// ID represents where the migration is in the order of those to be
 	// completed.
-	ID int
+	ID int64
 
 	// FileName is the file name of the migration.
 	FileName string
@@ -33,19 +36,54 @@ type Migration struct {
 	// Rollback is the rollback file for the current migration. There must
 	// always be one; otherwise an error will occur.
 	Rollback Rollback
+
+	// NoTransaction is set when the migration file's header contains the
+	// NoTransactionDirective. The migration is then ran on its own, outside
+	// of any transaction, and recorded in the history right after.
+	NoTransaction bool
+
+	// Up is set for migrations added with Register and is ran in place of
+	// FileContents.
+	Up MigrationFunc
+}
+
+// Checksum is the hex encoded SHA-256 hash of the migration's contents. It is
+// recorded in the migration history table so that edits to a migration file
+// after it has been ran can be detected.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256(m.FileContents)
+	return hex.EncodeToString(sum[:])
 }
 
 // RanMigration is a representation of a migration that was previously ran
 // into the database.
 type RanMigration struct {
 	// ID is the identifier of the migration that was ran.
-	ID int
+	ID int64
 
 	// FileName is the name of the migration.
 	FileName string
 
 	// Ran is when the migration was ran into the database.
 	Ran time.Time
+
+	// Checksum is the checksum of the migration's contents when it was ran.
+	// It is empty for migrations recorded before checksums were introduced.
+	Checksum string
+
+	// Duration is how long the migration took to run, to the millisecond.
+	// It, Host, User and Version are empty for migrations recorded before
+	// they were introduced.
+	Duration time.Duration
+
+	// Host is the name of the machine that ran the migration.
+	Host string
+
+	// User is the name of the operating system user that ran the migration.
+	User string
+
+	// Version is the version of migrator that ran the migration.
+	Version string
 }
 
 // Rollback is a rollback script related to a migration.
@@ -55,6 +93,14 @@ type Rollback struct {
 
 	// FileContents is the contents of the associated rollback.
 	FileContents []byte
+
+	// NoTransaction is set when the rollback file's header contains the
+	// NoTransactionDirective.
+	NoTransaction bool
+
+	// Down is set for rollbacks written in Go and is ran in place of
+	// FileContents.
+	Down MigrationFunc
 }
 
 // Configuration is an object where the configuration of migrator is stored.
@@ -71,19 +117,74 @@ type Configuration struct {
 	// are stored.
 	RollbacksDir string
 
+	// FS is the file system MigrationsDir and RollbacksDir are read from,
+	// such as an embed.FS holding migrations compiled into the binary. When
+	// it is nil they are read from the operating system's file system.
+	FS fs.FS
+
+	// GapPolicy is how gaps in the sequence of migration IDs are treated.
+	// Gaps are ignored by default.
+	GapPolicy GapPolicy
+
+	// OutOfOrderPolicy is how pending migrations with an ID lower than the
+	// highest applied migration are treated by Migrate and Status. They are
+	// applied by default.
+	OutOfOrderPolicy OutOfOrderPolicy
+
+	// Source, when set, is where migrations are found instead of
+	// MigrationsDir and RollbacksDir, which then need not be set.
+	Source MigrationSource
+
 	// MigrationToRollback is the migration that needs to be rolled back. This
 	// is useful when a development mistake may have been made.
 	MigrationToRollback string
+
+	// LockTimeout is how long to wait for another process to release the
+	// migration lock before giving up. Defaults to DefaultLockTimeout.
+	LockTimeout time.Duration
+
+	// DryRun, when set, makes Migrate and Rollback log the files and SQL
+	// that would run, in order, without writing anything to the database.
+	DryRun bool
+
+	// AllowDrift, when set, logs migration files that have changed since
+	// they were ran instead of refusing to continue. It exists for
+	// emergencies only.
+	AllowDrift bool
+
+	// TransactionMode is the strategy used to wrap migrations in database
+	// transactions. Defaults to the database servicer's default mode.
+	TransactionMode TransactionMode
+
+	// HistoryTable is the name of the table migrations are recorded in.
+	// Defaults to DefaultHistoryTable.
+	HistoryTable string
+
+	// HistorySchema is the schema the history table lives in. Defaults to
+	// the connection's current schema; SQLite only accepts main.
+	HistorySchema string
 }
 
 // Validate validates the configuration object ensuring it is ready to be used
 // within the Migrator application.
 func (c Configuration) Validate() error {
-	if c.DatabaseConnectionString == "" || c.MigrationsDir == "" || c.RollbacksDir == "" {
+	if c.DatabaseConnectionString == "" {
+		return ErrConfigurationInvalid
+	}
+
+	if c.Source == nil && (c.MigrationsDir == "" || c.RollbacksDir == "") {
 		return ErrConfigurationInvalid
 	}
 
-	return nil
+	return c.validateHistoryTable()
+}
+
+func (c Configuration) lockTimeout() time.Duration {
+	if c.LockTimeout <= 0 {
+		return DefaultLockTimeout
+	}
+
+	return c.LockTimeout
 }
 
 // NewMigrator initialises a set up migrator that can be used without having
@@ -105,110 +206,318 @@ type Migrator struct {
 	// DatabaseServicer is the service that performs all database operations.
 	DatabaseServicer DatabaseServicer
 
-	// LogServicer is the service that will perform all logging routines.
-	// This abstraction exists only to decouple the application from the
-	// implementation of log.Logger.
+	// LogServicer is the service that will perform all logging routines
+	// when Logger is not set. This abstraction exists only to decouple the
+	// application from the implementation of log.Logger.
 	LogServicer LogServicer
+
+	// Logger, when set, receives every event as a leveled message with
+	// key-value fields in place of LogServicer. See NewSlogLogger.
+	Logger Logger
+
+	// Hooks are callbacks invoked around each migrate and rollback run.
+	Hooks Hooks
 }
 
 // Migrate migrates all available migrations.
 func (m Migrator) Migrate() error {
-	var err error
-	var migrationFiles []Migration
-	var ranMigrations []RanMigration
+	return m.MigrateContext(context.Background())
+}
 
-	migrationFiles, ranMigrations, err = m.bootstrapMigrator()
-	if err != nil {
-		return err
-	}
+// MigrateContext migrates all available migrations. If the context is
+// cancelled or its deadline passes before a transaction is committed, that
+// transaction is rolled back and the context's error is returned. When
+// Config.DryRun is set the migrations that would run are logged instead.
+func (m Migrator) MigrateContext(ctx context.Context) error {
+	return m.migrate(ctx, pendingMigrations)
+}
+
+// MigrateTo migrates every pending migration up to and including the
+// migration with the specified ID, leaving later migrations unapplied.
+func (m Migrator) MigrateTo(id int64) error {
+	return m.MigrateToContext(context.Background(), id)
+}
 
-	defer m.DatabaseServicer.RollbackTransaction()
+// MigrateToContext migrates every pending migration up to and including the
+// migration with the specified ID, leaving later migrations unapplied.
+// Cancellation behaves as in MigrateContext.
+func (m Migrator) MigrateToContext(ctx context.Context, id int64) error {
+	return m.migrate(ctx, func(ms []Migration, r []RanMigration) ([]Migration, error) {
+		found := false
 
-	for _, migration := range migrationFiles {
-		if !migrationRan(ranMigrations, migration) {
-			err = m.DatabaseServicer.RunMigration(migration)
-			if err != nil {
-				return NewErrRunningMigration(migration, err)
+		for _, migration := range ms {
+			if migration.ID == id {
+				found = true
+				break
 			}
+		}
+
+		if !found {
+			return nil, NewErrMigrationTargetNotFound(id)
+		}
+
+		pending, err := pendingMigrations(ms, r)
+		if err != nil {
+			return nil, err
+		}
+
+		var toMigrate []Migration
 
-			err = m.DatabaseServicer.WriteMigrationHistory(migration)
-			if err != nil {
-				return NewErrRunningMigration(migration, err)
+		for _, migration := range pending {
+			if migration.ID <= id {
+				toMigrate = append(toMigrate, migration)
 			}
+		}
+
+		return toMigrate, nil
+	})
+}
 
-			m.LogServicer.Printf("migrated %s", migration.FileName)
+// migrationSelector chooses, from the migration files and history, the
+// migrations to run in the order they must be ran.
+type migrationSelector func(ms []Migration, r []RanMigration) ([]Migration, error)
+
+func (m Migrator) migrate(ctx context.Context, selectMigrations migrationSelector) (err error) {
+	if m.Config.DryRun {
+		return m.planMigrate(ctx, selectMigrations)
+	}
+
+	defer func() {
+		if err != nil {
+			m.Hooks.onFailure(err)
 		}
+	}()
+
+	migrationFiles, ranMigrations, err := m.bootstrapMigrator(ctx)
+	if err != nil {
+		return err
 	}
 
-	err = m.DatabaseServicer.CommitTransaction()
+	defer m.DatabaseServicer.ReleaseLock()
+
+	toMigrate, err := selectMigrations(migrationFiles, ranMigrations)
 	if err != nil {
-		return ErrCommittingTransaction
+		return err
+	}
+
+	if err = m.checkOutOfOrder(toMigrate, ranMigrations); err != nil {
+		return err
 	}
 
-	m.LogServicer.Printf("committed database transaction")
+	if err = m.Hooks.beforeRun(toMigrate); err != nil {
+		return err
+	}
+
+	outsideTransaction := func(migration Migration) bool {
+		return migration.NoTransaction
+	}
+
+	execution := executor()
+
+	return m.runSteps(ctx, phaseMigrate, m.transactionMode(), toMigrate, outsideTransaction, func(migration Migration) error {
+		start := time.Now()
+
+		err := m.DatabaseServicer.RunMigration(ctx, migration)
+		duration := time.Since(start)
+		if err == nil {
+			execution.Duration = duration
+			err = m.DatabaseServicer.WriteMigrationHistory(ctx, migration, execution)
+		}
+
+		if hookErr := m.Hooks.afterMigration(migration, duration, err); err == nil && hookErr != nil {
+			m.logger().Error("hook failed",
+				LogKeyPhase, phaseMigrate,
+				LogKeyMigrationID, migration.ID,
+				LogKeyFileName, migration.FileName,
+				LogKeyError, hookErr)
+			return hookErr
+		}
+
+		if err != nil {
+			m.logger().Error("migration failed",
+				LogKeyPhase, phaseMigrate,
+				LogKeyMigrationID, migration.ID,
+				LogKeyFileName, migration.FileName,
+				LogKeyDuration, duration,
+				LogKeyError, err)
+			return NewErrRunningMigration(migration, err)
+		}
+
+		m.logger().Info("migrated",
+			LogKeyPhase, phaseMigrate,
+			LogKeyMigrationID, migration.ID,
+			LogKeyFileName, migration.FileName,
+			LogKeyDuration, duration)
 
-	return nil
+		return nil
+	})
 }
 
 // Rollback rolls back a specified transaction.
 func (m Migrator) Rollback(name string) error {
-	migrationFiles, ranMigrations, err := m.bootstrapMigrator()
-	if err != nil {
-		return err
+	return m.RollbackContext(context.Background(), name)
+}
+
+// RollbackContext rolls back a specified transaction. If the context is
+// cancelled or its deadline passes before a transaction is committed, that
+// transaction is rolled back and the context's error is returned. When
+// Config.DryRun is set the rollback that would run is logged instead.
+func (m Migrator) RollbackContext(ctx context.Context, name string) error {
+	return m.rollback(ctx, m.transactionMode(), func(ms []Migration, r []RanMigration) ([]Migration, error) {
+		if name == "" {
+			return nil, nil
+		}
+
+		toRollback, err := rollbackTarget(name, ms, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Migration{toRollback}, nil
+	})
+}
+
+// RollbackSteps rolls back the latest n migrations in reverse order within a
+// single transaction, whatever the configured transaction mode, unless it is
+// TransactionModeNone. Rollbacks marked to run outside of a transaction still
+// run on their own.
+func (m Migrator) RollbackSteps(n int) error {
+	return m.RollbackStepsContext(context.Background(), n)
+}
+
+// RollbackStepsContext rolls back the latest n migrations in reverse order
+// within a single transaction, as RollbackSteps does. Cancellation behaves as
+// in RollbackContext.
+func (m Migrator) RollbackStepsContext(ctx context.Context, n int) error {
+	if n <= 0 {
+		return ErrInvalidRollbackSteps
 	}
 
-	defer m.DatabaseServicer.RollbackTransaction()
+	return m.rollback(ctx, m.batchTransactionMode(), func(ms []Migration, r []RanMigration) ([]Migration, error) {
+		if n > len(r) {
+			return nil, ErrTooManyRollbackSteps
+		}
 
-	if name != "" {
-		var toRollback Migration
+		return rollbackTargets(ms, latestRanMigrations(r)[:n])
+	})
+}
 
-		var latestMigrationID int
+// RollbackTo rolls back, in reverse order and within a single transaction as
+// RollbackSteps does, every migration ran after the migration with the
+// specified ID. The target migration itself stays applied; an ID of 0 rolls
+// back every migration.
+func (m Migrator) RollbackTo(id int64) error {
+	return m.RollbackToContext(context.Background(), id)
+}
 
-		for _, ranMigration := range ranMigrations {
-			if ranMigration.ID > latestMigrationID {
-				latestMigrationID = ranMigration.ID
+// RollbackToContext rolls back, in reverse order and within a single
+// transaction as RollbackSteps does, every migration ran after the migration
+// with the specified ID. Cancellation behaves as in RollbackContext.
+func (m Migrator) RollbackToContext(ctx context.Context, id int64) error {
+	return m.rollback(ctx, m.batchTransactionMode(), func(ms []Migration, r []RanMigration) ([]Migration, error) {
+		var toRollback []RanMigration
+		targetRan := id == 0
+
+		for _, ranMigration := range latestRanMigrations(r) {
+			if ranMigration.ID == id {
+				targetRan = true
 			}
-		}
 
-		for _, migration := range migrationFiles {
-			if migration.FileName == name {
-				toRollback = migration
-				break
+			if ranMigration.ID > id {
+				toRollback = append(toRollback, ranMigration)
 			}
 		}
 
-		if toRollback.ID != latestMigrationID {
-			return ErrNotLatestMigration
+		if !targetRan {
+			return nil, ErrRollbackTargetNotRan
 		}
 
-		err = m.DatabaseServicer.RollbackMigration(toRollback)
-		if err != nil {
-			return NewErrRunningRollback(toRollback.Rollback, err)
-		}
+		return rollbackTargets(ms, toRollback)
+	})
+}
+
+// rollbackSelector chooses, from the migration files and history, the
+// migrations to roll back in the order they must be rolled back.
+type rollbackSelector func(ms []Migration, r []RanMigration) ([]Migration, error)
 
-		err = m.DatabaseServicer.RemoveMigrationHistory(toRollback)
+// rollback rolls back the migrations chosen by selectRollbacks, wrapping them in
+// transactions as dictated by mode.
+func (m Migrator) rollback(ctx context.Context, mode TransactionMode, selectRollbacks rollbackSelector) (err error) {
+	if m.Config.DryRun {
+		return m.planRollback(ctx, selectRollbacks)
+	}
+
+	defer func() {
 		if err != nil {
-			return NewErrRunningRollback(toRollback.Rollback, err)
+			m.Hooks.onFailure(err)
 		}
+	}()
 
-		m.LogServicer.Printf("rolled back %s", toRollback.FileName)
+	migrationFiles, ranMigrations, err := m.bootstrapMigrator(ctx)
+	if err != nil {
+		return err
 	}
 
-	err = m.DatabaseServicer.CommitTransaction()
+	defer m.DatabaseServicer.ReleaseLock()
+
+	toRollback, err := selectRollbacks(migrationFiles, ranMigrations)
 	if err != nil {
-		return ErrCommittingTransaction
+		return err
 	}
 
-	m.LogServicer.Printf("committed database transaction")
+	if err = m.Hooks.beforeRun(toRollback); err != nil {
+		return err
+	}
 
-	return nil
-}
+	outsideTransaction := func(migration Migration) bool {
+		return migration.Rollback.NoTransaction
+	}
+
+	return m.runSteps(ctx, phaseRollback, mode, toRollback, outsideTransaction, func(migration Migration) error {
+		if err := m.Hooks.beforeRollback(migration); err != nil {
+			return err
+		}
 
-func (m Migrator) bootstrapMigrator() ([]Migration, []RanMigration, error) {
-	var migrationFiles []Migration
-	var ranMigrations []RanMigration
-	var err error
+		start := time.Now()
 
+		err := m.DatabaseServicer.RollbackMigration(ctx, migration)
+		if err == nil {
+			err = m.DatabaseServicer.RemoveMigrationHistory(ctx, migration)
+		}
+		duration := time.Since(start)
+
+		if hookErr := m.Hooks.afterRollback(migration, duration, err); err == nil && hookErr != nil {
+			m.logger().Error("hook failed",
+				LogKeyPhase, phaseRollback,
+				LogKeyMigrationID, migration.ID,
+				LogKeyFileName, migration.FileName,
+				LogKeyError, hookErr)
+			return hookErr
+		}
+
+		if err != nil {
+			m.logger().Error("rollback failed",
+				LogKeyPhase, phaseRollback,
+				LogKeyMigrationID, migration.ID,
+				LogKeyFileName, migration.FileName,
+				LogKeyDuration, duration,
+				LogKeyError, err)
+			return NewErrRunningRollback(migration.Rollback, err)
+		}
+
+		m.logger().Info("rolled back",
+			LogKeyPhase, phaseRollback,
+			LogKeyMigrationID, migration.ID,
+			LogKeyFileName, migration.FileName,
+			LogKeyDuration, duration)
+
+		return nil
+	})
+}
+
+// bootstrapMigrator acquires the migration lock and reads the migration files
+// and history. On success the caller is responsible for releasing the lock.
+func (m Migrator) bootstrapMigrator(ctx context.Context) (migrationFiles []Migration, ranMigrations []RanMigration, err error) {
 	if err = m.Config.Validate(); err != nil {
 		return migrationFiles, ranMigrations, err
 	}
@@ -217,15 +526,38 @@ func (m Migrator) bootstrapMigrator() ([]Migration, []RanMigration, error) {
 		return migrationFiles, ranMigrations, ErrDbServicerNotInitialised
 	}
 
-	// First thing that needs to be done is to create the migration history
-	// table.
-	h, err := m.DatabaseServicer.TryCreateHistoryTable()
+	if err = m.configureHistoryTable(); err != nil {
+		return migrationFiles, ranMigrations, err
+	}
+
+	// Take the migration lock before anything reads or writes the history
+	// table so concurrent runs cannot apply the same migration twice.
+	locked, err := m.DatabaseServicer.AcquireLock(ctx, m.Config.lockTimeout())
+	if err != nil {
+		return migrationFiles, ranMigrations, NewErrAcquiringLock(err)
+	}
+
+	if !locked {
+		return migrationFiles, ranMigrations, ErrMigrationLocked
+	}
+
+	m.logger().Info("acquired migration lock", LogKeyPhase, phaseBootstrap)
+
+	defer func() {
+		if err != nil {
+			m.DatabaseServicer.ReleaseLock()
+		}
+	}()
+
+	// With the lock held, the migration history table can safely be
+	// created.
+	h, err := m.DatabaseServicer.TryCreateHistoryTable(ctx)
 	if err != nil {
 		return migrationFiles, ranMigrations, NewErrCreatingHistoryTable(err)
 	}
 
 	if h {
-		m.LogServicer.Printf("created migration history table")
+		m.logger().Info("created migration history table", LogKeyPhase, phaseBootstrap)
 	}
 
 	migrationFiles, err = m.findMigrations()
@@ -233,144 +565,198 @@ func (m Migrator) bootstrapMigrator() ([]Migration, []RanMigration, error) {
 		return migrationFiles, ranMigrations, err
 	}
 
-	ranMigrations, err = m.DatabaseServicer.RanMigrations()
+	ranMigrations, err = m.DatabaseServicer.RanMigrations(ctx)
 	if err != nil {
 		return migrationFiles, ranMigrations, ErrUnableToRetrieveRanMigrations
 	}
 
-	m.LogServicer.Printf("located %d migration files", len(migrationFiles))
-	m.LogServicer.Printf("located %d previously ran migrations",
-		len(ranMigrations))
+	m.logger().Info("located migrations",
+		LogKeyPhase, phaseBootstrap,
+		"migration_files", len(migrationFiles),
+		"ran_migrations", len(ranMigrations))
 
-	// Sort the migration files by their ids.
-	sort.Sort(migrations(migrationFiles))
-
-	// Create a transaction to batch run the migrations.
-	if err = m.DatabaseServicer.BeginTransaction(); err != nil {
-		m.LogServicer.Printf("database error creating transaction: %s", err)
-		return migrationFiles, ranMigrations, ErrCreatingDbTransaction
+	if err = m.verifyChecksums(migrationFiles, ranMigrations); err != nil {
+		return migrationFiles, ranMigrations, err
 	}
 
-	m.LogServicer.Printf("database transaction created")
+	// Sort the migration files by their ids.
+	sort.Sort(migrations(migrationFiles))
 
-	return migrationFiles, ranMigrations, err
+	return migrationFiles, ranMigrations, nil
 }
 
+// findMigrations returns the migrations from the configured source along with
+// any that were added with Register, once they have passed validation.
 func (m Migrator) findMigrations() ([]Migration, error) {
-	migrationFiles, err := ioutil.ReadDir(m.Config.MigrationsDir)
+	source := m.source()
+
+	found, err := discoverMigrations(source, m.logger())
 	if err != nil {
-		return nil, NewErrSearchingDir(m.Config.MigrationsDir, err)
+		return nil, err
+	}
+
+	if err = m.validateMigrations(source, found, false); err != nil {
+		return nil, err
 	}
 
-	if len(migrationFiles) == 0 {
-		return nil, ErrNoMigrationsInDir
+	return found, nil
+}
+
+func discoverMigrations(source MigrationSource, l Logger) ([]Migration, error) {
+	registered := registeredMigrations()
+
+	found, err := source.Migrations(l)
+	if err == ErrNoMigrationsInDir && len(registered) > 0 {
+		err = nil
 	}
 
-	rollbackFiles, err := ioutil.ReadDir(m.Config.RollbacksDir)
 	if err != nil {
-		return nil, NewErrSearchingDir(m.Config.RollbacksDir, err)
+		return nil, err
 	}
 
-	if len(rollbackFiles) == 0 {
-		return nil, ErrNoRollbacksInDir
+	return append(found, registered...), nil
+}
+
+func (m Migrator) source() MigrationSource {
+	if m.Config.Source != nil {
+		return m.Config.Source
+	}
+
+	if m.Config.FS != nil {
+		return NewFSSource(m.Config.FS, m.Config.MigrationsDir, m.Config.RollbacksDir)
 	}
 
-	migrations := make([]Migration, len(migrationFiles))
+	return NewDirectorySource(m.Config.MigrationsDir, m.Config.RollbacksDir)
+}
 
-	for _, migration := range migrationFiles {
-		// Each migration/rollback file name should be of format:
-		// id_name_up/down.sql. If they are not, we should not include them.
-		fileNameParts := strings.Split(migration.Name(), "_")
+type migrations []Migration
 
-		if !migrationActionEquals(fileNameParts, "up") {
-			m.LogServicer.Printf("skipping file %s, does not have an appropriate file name\n", migration.Name())
+func (m migrations) Len() int {
+	return len(m)
+}
+
+func (m migrations) Less(i, j int) bool {
+	return m[i].ID < m[j].ID
+}
+
+func (m migrations) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+// verifyChecksums compares the checksums recorded in the migration history
+// with the migration files on disk, failing with ErrMigrationDrift when any
+// of them have changed unless drift has been allowed.
+func (m Migrator) verifyChecksums(ms []Migration, r []RanMigration) error {
+	var drifted []string
+
+	for _, ranMigration := range r {
+		if ranMigration.Checksum == "" {
 			continue
 		}
 
-		rollbackName := strings.Join(fileNameParts[0:2], "_")
-		rollback, err := m.findRollback(rollbackName, rollbackFiles)
-		if err != nil {
-			return nil, err
+		for _, migration := range ms {
+			if migration.FileName == ranMigration.FileName {
+				if migration.Checksum() != ranMigration.Checksum {
+					drifted = append(drifted, migration.FileName)
+				}
+				break
+			}
 		}
+	}
 
-		migrationID, err := strconv.Atoi(fileNameParts[0])
-		if err != nil {
-			return nil, NewErrInvalidMigrationID(migration.Name(), err)
-		}
+	if len(drifted) == 0 {
+		return nil
+	}
 
-		filePath := fmt.Sprintf("%s/%s", m.Config.MigrationsDir, migration.Name())
-		file, err := ioutil.ReadFile(filePath)
-		if err != nil {
-			return nil, NewErrReadingFile(migration.Name(), err)
+	if m.Config.AllowDrift {
+		for _, d := range drifted {
+			m.logger().Warn("allowing drift, migration has changed since it was ran",
+				LogKeyPhase, phaseBootstrap,
+				LogKeyFileName, d)
 		}
 
-		migration := Migration{
-			ID:           migrationID,
-			FileName:     migration.Name(),
-			FileContents: file,
-			Rollback:     rollback,
-		}
-		migrations[len(migrations)-1] = migration
+		return nil
 	}
 
-	return migrations, nil
+	return NewErrMigrationDrift(drifted)
 }
 
-func (m Migrator) findRollback(migName string, rbs []os.FileInfo) (Rollback, error) {
-	for _, r := range rbs {
-		// Each migration/rollback file name should be of format:
-		// id_name_up/down.sql. If they are not, we should not include them.
-		rollbackNameParts := strings.Split(r.Name(), "_")
+// pendingMigrations returns, in order, the migrations that have not yet been
+// ran into the database.
+func pendingMigrations(ms []Migration, r []RanMigration) ([]Migration, error) {
+	var pending []Migration
 
-		if !migrationActionEquals(rollbackNameParts, "down") {
-			m.LogServicer.Printf("skipping file %s, does not have an appropriate file name\n", r.Name())
-			continue
+	for _, m := range ms {
+		if !migrationRan(r, m) {
+			pending = append(pending, m)
 		}
+	}
 
-		filePath := fmt.Sprintf("%s/%s", m.Config.RollbacksDir, r.Name())
-		file, err := ioutil.ReadFile(filePath)
-		if err != nil {
-			return Rollback{}, NewErrReadingFile(r.Name(), err)
+	return pending, nil
+}
+
+// rollbackTarget finds the migration with the specified file name, ensuring
+// it is the latest one that was ran.
+func rollbackTarget(name string, ms []Migration, r []RanMigration) (Migration, error) {
+	var toRollback Migration
+
+	var latestMigrationID int64
+
+	for _, ranMigration := range r {
+		if ranMigration.ID > latestMigrationID {
+			latestMigrationID = ranMigration.ID
 		}
+	}
 
-		rollbackName := strings.Join(rollbackNameParts[0:2], "_")
-		if strings.ToLower(rollbackName) == strings.ToLower(migName) {
-			return Rollback{
-				FileName:     r.Name(),
-				FileContents: file,
-			}, nil
+	for _, migration := range ms {
+		if migration.FileName == name {
+			toRollback = migration
+			break
 		}
 	}
 
-	return Rollback{}, NewErrMissingRollbackFile(migName)
-}
+	if toRollback.ID != latestMigrationID {
+		return Migration{}, ErrNotLatestMigration
+	}
 
-func migrationActionEquals(fileParts []string, ext string) bool {
-	return len(fileParts) == 3 && removeFileExtension(fileParts[2]) == ext
+	return toRollback, nil
 }
 
-func removeFileExtension(fp string) string {
-	fileParts := strings.Split(fp, ".")
+// latestRanMigrations returns the ran migrations ordered from the most to the
+// least recent ID.
+func latestRanMigrations(r []RanMigration) []RanMigration {
+	latest := make([]RanMigration, len(r))
+	copy(latest, r)
 
-	if len(fileParts) > 0 {
-		return strings.ToLower(fileParts[0])
-	}
+	sort.SliceStable(latest, func(i, j int) bool {
+		return latest[i].ID > latest[j].ID
+	})
 
-	return fp
+	return latest
 }
 
-type migrations []Migration
+// rollbackTargets finds the migration file for every ran migration, keeping
+// their order. Every one of them must have a file so that nothing is skipped.
+func rollbackTargets(ms []Migration, r []RanMigration) ([]Migration, error) {
+	toRollback := make([]Migration, 0, len(r))
 
-func (m migrations) Len() int {
-	return len(m)
-}
+	for _, ranMigration := range r {
+		found := false
 
-func (m migrations) Less(i, j int) bool {
-	return m[i].ID < m[j].ID
-}
+		for _, migration := range ms {
+			if migration.FileName == ranMigration.FileName {
+				toRollback = append(toRollback, migration)
+				found = true
+				break
+			}
+		}
 
-func (m migrations) Swap(i, j int) {
-	m[i], m[j] = m[j], m[i]
+		if !found {
+			return nil, NewErrMissingRollbackFile(ranMigration.FileName)
+		}
+	}
+
+	return toRollback, nil
 }
 
 func migrationRan(r []RanMigration, m Migration) bool {
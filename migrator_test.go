@@ -1,10 +1,14 @@
 package migrator_test
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/bunsenapp/migrator"
 	"github.com/bunsenapp/migrator/mock"
@@ -287,7 +291,7 @@ func TestWhenMigrationIsSuccessfullyRanInTheMigrationIsWrittenToHistoryTable(t *
 	var migrationHistoryWritten bool
 
 	db := mock.WorkingMockDatabaseServicer()
-	db.WriteMigrationHistoryFunc = func(m migrator.Migration) error {
+	db.WriteMigrationHistoryFunc = func(m migrator.Migration, e migrator.Execution) error {
 		migrationHistoryWritten = true
 		return nil
 	}
@@ -300,6 +304,43 @@ func TestWhenMigrationIsSuccessfullyRanInTheMigrationIsWrittenToHistoryTable(t *
 	}
 }
 
+func TestExecutionIsWrittenToHistoryTableWithTheMigration(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	migrator.Version = "v1.2.3"
+	defer func() { migrator.Version = "" }()
+
+	var execution migrator.Execution
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	db.WriteMigrationHistoryFunc = func(m migrator.Migration, e migrator.Execution) error {
+		execution = e
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	if execution.Version != "v1.2.3" {
+		t.Errorf("expected version v1.2.3, got %q", execution.Version)
+	}
+
+	if execution.Duration < 5*time.Millisecond {
+		t.Errorf("expected a duration of at least 5ms, got %s", execution.Duration)
+	}
+
+	if host, _ := os.Hostname(); execution.Host != host {
+		t.Errorf("expected host %q, got %q", host, execution.Host)
+	}
+}
+
 func TestCommitErrorIsReturned(t *testing.T) {
 	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
 	defer cleanUp()
@@ -508,3 +549,687 @@ func TestAnErrorWhilstRollingBackTheMigrationResultsInTheTransactionBeingRolledB
 		t.Errorf("transaction was not rolled back when it should have been")
 	}
 }
+
+func TestCancelledContextResultsInTheTransactionBeingRolledBack(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	migrationRan := false
+	transactionRolledBack := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		migrationRan = true
+		return nil
+	}
+	db.RollbackTransactionFunc = func() error {
+		transactionRolledBack = true
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.MigrateContext(ctx); err != context.Canceled {
+		t.Errorf("error returned was not correct")
+	}
+	if migrationRan {
+		t.Errorf("migration ran when it shouldn't have been")
+	}
+	if !transactionRolledBack {
+		t.Errorf("transaction was not rolled back when it should have been")
+	}
+}
+
+func TestCancelledContextDuringRollbackIsNotCommitted(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	transactionCommitted := false
+	ctx, cancel := context.WithCancel(context.Background())
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID: 1,
+			},
+		}, nil
+	}
+	db.RollbackMigrationFunc = func(m migrator.Migration) error {
+		cancel()
+		return nil
+	}
+	db.CommitTransactionFunc = func() error {
+		transactionCommitted = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.RollbackContext(ctx, "1_first-migration_up.sql"); err != context.Canceled {
+		t.Errorf("error returned was not correct")
+	}
+	if transactionCommitted {
+		t.Errorf("transaction was committed when it shouldn't have been")
+	}
+}
+
+func TestMigrationLockIsAcquiredBeforeRanMigrationsAreRetrieved(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	lockAcquired := false
+	lockAcquiredFirst := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.AcquireLockFunc = func(timeout time.Duration) (bool, error) {
+		lockAcquired = true
+		return true, nil
+	}
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		lockAcquiredFirst = lockAcquired
+		return []migrator.RanMigration{}, nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Migrate()
+
+	if !lockAcquiredFirst {
+		t.Errorf("lock was not acquired before reading the migration history")
+	}
+}
+
+func TestConfiguredLockTimeoutIsUsed(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.LockTimeout = 5 * time.Second
+
+	var lockTimeout time.Duration
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.AcquireLockFunc = func(timeout time.Duration) (bool, error) {
+		lockTimeout = timeout
+		return true, nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Migrate()
+
+	if lockTimeout != config.LockTimeout {
+		t.Errorf("configured lock timeout was not used")
+	}
+}
+
+func TestLockHeldByAnotherProcessResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	migrationRan := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.AcquireLockFunc = func(timeout time.Duration) (bool, error) {
+		return false, nil
+	}
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		migrationRan = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != migrator.ErrMigrationLocked {
+		t.Errorf("error returned was not correct")
+	}
+	if migrationRan {
+		t.Errorf("migration ran when it shouldn't have been")
+	}
+}
+
+func TestErrorAcquiringLockIsReturned(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.AcquireLockFunc = func(timeout time.Duration) (bool, error) {
+		return false, errors.New("foo")
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	err := m.Migrate()
+	if _, ok := err.(migrator.ErrAcquiringLock); !ok {
+		t.Errorf("error returned was not correct")
+	}
+}
+
+func TestLockIsReleasedWhenBootstrappingFails(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	lockReleased := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return nil, errors.New("boo")
+	}
+	db.ReleaseLockFunc = func() error {
+		lockReleased = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Migrate()
+
+	if !lockReleased {
+		t.Errorf("lock was not released after an error occurred")
+	}
+}
+
+func TestLockIsReleasedAfterASuccessfulMigration(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	lockReleased := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.ReleaseLockFunc = func() error {
+		lockReleased = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Errorf("error was returned when it shouldn't have been: %s", err)
+	}
+
+	if !lockReleased {
+		t.Errorf("lock was not released after migrating")
+	}
+}
+
+// threeRanMigrations creates three migration files and returns a mock
+// database servicer reporting all of them as ran, recording the order in
+// which they are rolled back.
+func threeRanMigrations(config migrator.Configuration, rolledBack *[]int64) mock.MockDatabaseServicer {
+	for i := 2; i <= 3; i++ {
+		os.Create(fmt.Sprintf("%s/%d_migration_up.sql", config.MigrationsDir, i))
+		os.Create(fmt.Sprintf("%s/%d_migration_down.sql", config.RollbacksDir, i))
+	}
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       2,
+				FileName: "2_migration_up.sql",
+			},
+			{
+				ID:       1,
+				FileName: "1_first-migration_up.sql",
+			},
+			{
+				ID:       3,
+				FileName: "3_migration_up.sql",
+			},
+		}, nil
+	}
+	db.RollbackMigrationFunc = func(m migrator.Migration) error {
+		*rolledBack = append(*rolledBack, m.ID)
+		return nil
+	}
+
+	return db
+}
+
+func TestRollbackStepsRollsBackInReverseOrder(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var rolledBack []int64
+	transactions := 0
+
+	db := threeRanMigrations(config, &rolledBack)
+	db.BeginTransactionFunc = func() error {
+		transactions++
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.RollbackSteps(2); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if fmt.Sprint(rolledBack) != "[3 2]" {
+		t.Errorf("migrations were not rolled back in reverse order: %v", rolledBack)
+	}
+	if transactions != 1 {
+		t.Errorf("expected 1 transaction, got %d", transactions)
+	}
+}
+
+func TestMultiStepRollbacksUseOneTransactionWhateverTheDefaultMode(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var rolledBack []int64
+	var events []string
+
+	db := threeRanMigrations(config, &rolledBack)
+	db.DefaultTransactionModeFunc = func() migrator.TransactionMode {
+		return migrator.TransactionModePerMigration
+	}
+	db.BeginTransactionFunc = func() error {
+		events = append(events, "begin")
+		return nil
+	}
+	db.RollbackMigrationFunc = func(m migrator.Migration) error {
+		if m.ID == 2 {
+			return errors.New("foo")
+		}
+		events = append(events, "rollback")
+		return nil
+	}
+	db.CommitTransactionFunc = func() error {
+		events = append(events, "commit")
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if _, ok := m.RollbackTo(0).(migrator.ErrRunningRollback); !ok {
+		t.Fatalf("error returned was not correct")
+	}
+
+	if fmt.Sprint(events) != "[begin rollback]" {
+		t.Errorf("rollbacks were not ran in one transaction: %v", events)
+	}
+}
+
+func TestRollbackStepsGreaterThanRanMigrationsResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var rolledBack []int64
+
+	m := NewConfiguredMigrator(config, threeRanMigrations(config, &rolledBack), mock.MockLogServicer())
+	if err := m.RollbackSteps(4); err != migrator.ErrTooManyRollbackSteps {
+		t.Errorf("error returned was not correct")
+	}
+	if len(rolledBack) != 0 {
+		t.Errorf("migrations were rolled back when they shouldn't have been")
+	}
+}
+
+func TestRollbackStepsMustBePositive(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), mock.MockLogServicer())
+	if err := m.RollbackSteps(0); err != migrator.ErrInvalidRollbackSteps {
+		t.Errorf("error returned was not correct")
+	}
+}
+
+func TestRollbackToRollsBackEveryLaterMigration(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var rolledBack []int64
+
+	m := NewConfiguredMigrator(config, threeRanMigrations(config, &rolledBack), mock.MockLogServicer())
+	if err := m.RollbackTo(1); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if fmt.Sprint(rolledBack) != "[3 2]" {
+		t.Errorf("migrations were not rolled back in reverse order: %v", rolledBack)
+	}
+}
+
+func TestRollbackToANotRanMigrationResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var rolledBack []int64
+
+	m := NewConfiguredMigrator(config, threeRanMigrations(config, &rolledBack), mock.MockLogServicer())
+	if err := m.RollbackTo(7); err != migrator.ErrRollbackTargetNotRan {
+		t.Errorf("error returned was not correct")
+	}
+}
+
+func TestRollbackToWithAMissingMigrationFileResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	var rolledBack []int64
+
+	db := threeRanMigrations(config, &rolledBack)
+	os.Remove(fmt.Sprintf("%s/2_migration_up.sql", config.MigrationsDir))
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	err := m.RollbackTo(0)
+	if _, ok := err.(migrator.ErrMissingRollbackFile); !ok {
+		t.Errorf("error returned was not correct: %v", err)
+	}
+	if len(rolledBack) != 0 {
+		t.Errorf("migrations were rolled back when they shouldn't have been")
+	}
+}
+
+func TestMigrateToStopsAfterTheTargetMigration(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	for i := 2; i <= 3; i++ {
+		os.Create(fmt.Sprintf("%s/%d_migration_up.sql", config.MigrationsDir, i))
+		os.Create(fmt.Sprintf("%s/%d_migration_down.sql", config.RollbacksDir, i))
+	}
+
+	var migrated []int64
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		migrated = append(migrated, m.ID)
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.MigrateTo(2); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if fmt.Sprint(migrated) != "[1 2]" {
+		t.Errorf("migrations ran were not correct: %v", migrated)
+	}
+}
+
+func TestMigrateToAnUnknownMigrationResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	migrationRan := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		migrationRan = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	err := m.MigrateTo(42)
+	if _, ok := err.(migrator.ErrMigrationTargetNotFound); !ok {
+		t.Errorf("error returned was not correct: %v", err)
+	}
+	if migrationRan {
+		t.Errorf("migration ran when it shouldn't have been")
+	}
+}
+
+func TestPerMigrationTransactionModeCommitsEachMigration(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.TransactionMode = migrator.TransactionModePerMigration
+	os.Create(fmt.Sprintf("%s/2_migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/2_migration_down.sql", config.RollbacksDir))
+
+	var events []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.BeginTransactionFunc = func() error {
+		events = append(events, "begin")
+		return nil
+	}
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		if m.ID == 2 {
+			return errors.New("foo")
+		}
+		events = append(events, "run")
+		return nil
+	}
+	db.CommitTransactionFunc = func() error {
+		events = append(events, "commit")
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	err := m.Migrate()
+	if _, ok := err.(migrator.ErrRunningMigration); !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if fmt.Sprint(events) != "[begin run commit begin]" {
+		t.Errorf("transactions were not per migration: %v", events)
+	}
+}
+
+func TestDatabaseServicerDefaultTransactionModeIsUsed(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	transactionCreated := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.DefaultTransactionModeFunc = func() migrator.TransactionMode {
+		return migrator.TransactionModeNone
+	}
+	db.BeginTransactionFunc = func() error {
+		transactionCreated = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if transactionCreated {
+		t.Errorf("transaction was created when it shouldn't have been")
+	}
+}
+
+func TestConfiguredTransactionModeOverridesTheDefault(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.TransactionMode = migrator.TransactionModeAll
+	transactionCreated := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.DefaultTransactionModeFunc = func() migrator.TransactionMode {
+		return migrator.TransactionModeNone
+	}
+	db.BeginTransactionFunc = func() error {
+		transactionCreated = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Migrate()
+
+	if !transactionCreated {
+		t.Errorf("transaction was not created when it should have been")
+	}
+}
+
+func TestNoTransactionDirectiveRunsMigrationOutsideTheBatchTransaction(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	ioutil.WriteFile(fmt.Sprintf("%s/2_concurrent-index_up.sql", config.MigrationsDir),
+		[]byte("-- Builds the index without locking the table.\n-- migrator:no-transaction\nCREATE INDEX CONCURRENTLY foo ON bar (baz);"), 0600)
+	os.Create(fmt.Sprintf("%s/2_concurrent-index_down.sql", config.RollbacksDir))
+	os.Create(fmt.Sprintf("%s/3_migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/3_migration_down.sql", config.RollbacksDir))
+
+	var events []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.BeginTransactionFunc = func() error {
+		events = append(events, "begin")
+		return nil
+	}
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		events = append(events, fmt.Sprintf("run %d", m.ID))
+		return nil
+	}
+	db.WriteMigrationHistoryFunc = func(m migrator.Migration, e migrator.Execution) error {
+		events = append(events, fmt.Sprintf("history %d", m.ID))
+		return nil
+	}
+	db.CommitTransactionFunc = func() error {
+		events = append(events, "commit")
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	expected := "[begin run 1 history 1 commit run 2 history 2 begin run 3 history 3 commit]"
+	if fmt.Sprint(events) != expected {
+		t.Errorf("migration was not ran outside of the transaction: %v", events)
+	}
+}
+
+func TestNoTransactionDirectiveAfterAStatementIsIgnored(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	os.Remove(fmt.Sprintf("%s/1_first-migration_up.sql", config.MigrationsDir))
+	ioutil.WriteFile(fmt.Sprintf("%s/1_first-migration_up.sql", config.MigrationsDir),
+		[]byte("CREATE TABLE foo (id INT);\n-- migrator:no-transaction\n"), 0600)
+
+	var noTransaction bool
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		noTransaction = m.NoTransaction
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	m.Migrate()
+
+	if noTransaction {
+		t.Errorf("directive after the first statement was honoured")
+	}
+}
+
+func TestSingleFileMigrationIsSplitIntoItsUpAndDownSections(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	ioutil.WriteFile(fmt.Sprintf("%s/2_add-users.sql", config.MigrationsDir),
+		[]byte("-- +migrate Up\nCREATE TABLE users (id INT);\n\n-- +migrate Down\n-- migrator:no-transaction\nDROP TABLE users;\n"), 0600)
+
+	var ran migrator.Migration
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		ran = m
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if ran.ID != 2 || ran.FileName != "2_add-users.sql" {
+		t.Fatalf("single-file migration was not ran: %+v", ran)
+	}
+
+	if string(ran.FileContents) != "CREATE TABLE users (id INT);\n\n" {
+		t.Errorf("up section was not correct: %q", ran.FileContents)
+	}
+
+	if string(ran.Rollback.FileContents) != "-- migrator:no-transaction\nDROP TABLE users;\n" {
+		t.Errorf("down section was not correct: %q", ran.Rollback.FileContents)
+	}
+
+	if ran.NoTransaction || !ran.Rollback.NoTransaction {
+		t.Errorf("directives were not read from their own sections")
+	}
+}
+
+func TestSingleFileMigrationWithoutADownSectionResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	ioutil.WriteFile(fmt.Sprintf("%s/2_add-users.sql", config.MigrationsDir),
+		[]byte("-- +migrate Up\nCREATE TABLE users (id INT);\n"), 0600)
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), mock.MockLogServicer())
+	if _, ok := m.Migrate().(migrator.ErrMissingRollbackFile); !ok {
+		t.Errorf("missing down section did not result in an error")
+	}
+}
+
+func TestSingleFileMigrationsDoNotNeedTheRollbacksDirectory(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationAndDirectories()
+	defer cleanUp()
+
+	os.RemoveAll(config.RollbacksDir)
+	ioutil.WriteFile(fmt.Sprintf("%s/1_add-users.sql", config.MigrationsDir),
+		[]byte("-- +migrate Up\nCREATE TABLE users (id INT);\n-- +migrate Down\nDROP TABLE users;\n"), 0600)
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Errorf("error returned when it shouldn't have been: %s", err)
+	}
+}
+
+func TestRegisteredMigrationsAreRanInOrderWithMigrationFiles(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+	defer migrator.ResetRegistry()
+
+	os.Create(fmt.Sprintf("%s/3_migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/3_migration_down.sql", config.RollbacksDir))
+
+	noop := func(tx *sql.Tx) error { return nil }
+	migrator.Register(2, "backfill-users", noop, noop)
+
+	var ran, written []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		ran = append(ran, m.FileName)
+		return nil
+	}
+	db.WriteMigrationHistoryFunc = func(m migrator.Migration, e migrator.Execution) error {
+		written = append(written, m.FileName)
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	expected := "[1_first-migration_up.sql 2_backfill-users.go 3_migration_up.sql]"
+	if fmt.Sprint(ran) != expected {
+		t.Errorf("registered migration was not ran in order: %v", ran)
+	}
+
+	if fmt.Sprint(written) != expected {
+		t.Errorf("registered migration was not written to the history table: %v", written)
+	}
+}
+
+func TestRegisteringAMigrationTwiceResultsInAPanic(t *testing.T) {
+	defer migrator.ResetRegistry()
+
+	noop := func(tx *sql.Tx) error { return nil }
+	migrator.Register(1, "first", noop, noop)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a duplicate migration did not panic")
+		}
+	}()
+
+	migrator.Register(1, "second", noop, noop)
+}
@@ -1,20 +1,37 @@
 package mock
 
-import "github.com/bunsenapp/migrator"
+import (
+	"context"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+)
 
 // WorkingMockDatabaseServicer returns a working mock database servicer
 // that will not cause any panics due to invalid pointer references.
 func WorkingMockDatabaseServicer() MockDatabaseServicer {
 	return MockDatabaseServicer{
+		AcquireLockFunc: func(timeout time.Duration) (bool, error) {
+			return true, nil
+		},
 		BeginTransactionFunc: func() error {
 			return nil
 		},
 		CommitTransactionFunc: func() error {
 			return nil
 		},
+		DefaultTransactionModeFunc: func() migrator.TransactionMode {
+			return migrator.TransactionModeAll
+		},
+		HistoryTableExistsFunc: func() (bool, error) {
+			return true, nil
+		},
 		RanMigrationsFunc: func() ([]migrator.RanMigration, error) {
 			return []migrator.RanMigration{}, nil
 		},
+		ReleaseLockFunc: func() error {
+			return nil
+		},
 		RemoveMigrationHistoryFunc: func(m migrator.Migration) error {
 			return nil
 		},
@@ -30,12 +47,16 @@ func WorkingMockDatabaseServicer() MockDatabaseServicer {
 		TryCreateHistoryTableFunc: func() (bool, error) {
 			return true, nil
 		},
-		WriteMigrationHistoryFunc: func(m migrator.Migration) error {
+		WriteMigrationHistoryFunc: func(m migrator.Migration, e migrator.Execution) error {
 			return nil
 		},
 	}
 }
 
+// AcquireLockFunc is a function type that allows custom responses to be
+// returned from the AcquireLock call.
+type AcquireLockFunc func(timeout time.Duration) (bool, error)
+
 // BeginTransactionFunc is a function type that allows custom responses to be
 // returned from the BeginTransaction call.
 type BeginTransactionFunc func() error
@@ -44,10 +65,22 @@ type BeginTransactionFunc func() error
 // returned from the CommitTransaction call.
 type CommitTransactionFunc func() error
 
+// DefaultTransactionModeFunc is a function type that allows custom responses
+// to be returned from the DefaultTransactionMode call.
+type DefaultTransactionModeFunc func() migrator.TransactionMode
+
+// HistoryTableExistsFunc is a function type that allows custom responses to
+// be returned from the HistoryTableExists call.
+type HistoryTableExistsFunc func() (bool, error)
+
 // RanMigrationsFunc is a function type that allows custom responses to be
 // returned from the RanMigrations call.
 type RanMigrationsFunc func() ([]migrator.RanMigration, error)
 
+// ReleaseLockFunc is a function type that allows custom responses to be
+// returned from the ReleaseLock call.
+type ReleaseLockFunc func() error
+
 // RemoveMigrationHistoryFunc is a function type that allows custom responses
 // to be returned from the RemoveMigrationHistory call.
 type RemoveMigrationHistoryFunc func(m migrator.Migration) error
@@ -70,14 +103,18 @@ type TryCreateHistoryTableFunc func() (bool, error)
 
 // WriteMigrationHistoryFunc is a function type that allows custom responses
 // to be returned from the WriteMigrationHistory call.
-type WriteMigrationHistoryFunc func(m migrator.Migration) error
+type WriteMigrationHistoryFunc func(m migrator.Migration, e migrator.Execution) error
 
 // MockDatabaseServicer is a mocked implementation of the DatabaseServicer
 // interface.
 type MockDatabaseServicer struct {
+	AcquireLockFunc            AcquireLockFunc
 	BeginTransactionFunc       BeginTransactionFunc
 	CommitTransactionFunc      CommitTransactionFunc
+	DefaultTransactionModeFunc DefaultTransactionModeFunc
+	HistoryTableExistsFunc     HistoryTableExistsFunc
 	RanMigrationsFunc          RanMigrationsFunc
+	ReleaseLockFunc            ReleaseLockFunc
 	RemoveMigrationHistoryFunc RemoveMigrationHistoryFunc
 	RollbackMigrationFunc      RollbackMigrationFunc
 	RollbackTransactionFunc    RollbackTransactionFunc
@@ -86,8 +123,13 @@ type MockDatabaseServicer struct {
 	WriteMigrationHistoryFunc  WriteMigrationHistoryFunc
 }
 
+// AcquireLock fakes taking the migration lock.
+func (m MockDatabaseServicer) AcquireLock(ctx context.Context, timeout time.Duration) (bool, error) {
+	return m.AcquireLockFunc(timeout)
+}
+
 // BeginTransaction creates a fake database transaction.
-func (m MockDatabaseServicer) BeginTransaction() error {
+func (m MockDatabaseServicer) BeginTransaction(ctx context.Context) error {
 	return m.BeginTransactionFunc()
 }
 
@@ -96,18 +138,34 @@ func (m MockDatabaseServicer) CommitTransaction() error {
 	return m.CommitTransactionFunc()
 }
 
+// DefaultTransactionMode fakes the database servicer's default transaction
+// mode.
+func (m MockDatabaseServicer) DefaultTransactionMode() migrator.TransactionMode {
+	return m.DefaultTransactionModeFunc()
+}
+
+// HistoryTableExists fakes the check for the migration history table.
+func (m MockDatabaseServicer) HistoryTableExists(ctx context.Context) (bool, error) {
+	return m.HistoryTableExistsFunc()
+}
+
 // RanMigrations runs a fake migration check.
-func (m MockDatabaseServicer) RanMigrations() ([]migrator.RanMigration, error) {
+func (m MockDatabaseServicer) RanMigrations(ctx context.Context) ([]migrator.RanMigration, error) {
 	return m.RanMigrationsFunc()
 }
 
+// ReleaseLock fakes releasing the migration lock.
+func (m MockDatabaseServicer) ReleaseLock() error {
+	return m.ReleaseLockFunc()
+}
+
 // RemoveMigrationHistory fakes the removal of a specified migration.
-func (m MockDatabaseServicer) RemoveMigrationHistory(mi migrator.Migration) error {
+func (m MockDatabaseServicer) RemoveMigrationHistory(ctx context.Context, mi migrator.Migration) error {
 	return m.RemoveMigrationHistoryFunc(mi)
 }
 
 // RollbackMigration runs a fake rollback on a migration.
-func (m MockDatabaseServicer) RollbackMigration(mi migrator.Migration) error {
+func (m MockDatabaseServicer) RollbackMigration(ctx context.Context, mi migrator.Migration) error {
 	return m.RollbackMigrationFunc(mi)
 }
 
@@ -117,18 +175,18 @@ func (m MockDatabaseServicer) RollbackTransaction() error {
 }
 
 // RunMigration runs a fake database migration.
-func (m MockDatabaseServicer) RunMigration(mi migrator.Migration) error {
+func (m MockDatabaseServicer) RunMigration(ctx context.Context, mi migrator.Migration) error {
 	return m.RunMigrationFunc(mi)
 }
 
 // TryCreateHistoryTable fakes the method call that will try to create
 // the migration history table.
-func (m MockDatabaseServicer) TryCreateHistoryTable() (bool, error) {
+func (m MockDatabaseServicer) TryCreateHistoryTable(ctx context.Context) (bool, error) {
 	return m.TryCreateHistoryTableFunc()
 }
 
 // WriteMigrationHistory fakes the method call that will write a migration
 // to the migration history table.
-func (m MockDatabaseServicer) WriteMigrationHistory(mi migrator.Migration) error {
-	return m.WriteMigrationHistoryFunc(mi)
+func (m MockDatabaseServicer) WriteMigrationHistory(ctx context.Context, mi migrator.Migration, e migrator.Execution) error {
+	return m.WriteMigrationHistoryFunc(mi, e)
 }
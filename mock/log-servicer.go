@@ -11,3 +11,8 @@ import (
 func MockLogServicer() migrator.LogServicer {
 	return log.New(ioutil.Discard, "", 0)
 }
+
+// MockLogger generates a migrator.Logger that does not output anywhere.
+func MockLogger() migrator.Logger {
+	return migrator.NewStdLogger(MockLogServicer())
+}
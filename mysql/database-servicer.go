@@ -1,15 +1,31 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/internal/sqlutil"
 
 	// Import the required MySQL driver.
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// ErrNoTransaction is an error that is raised when a transaction is committed
+// or rolled back without one having been started.
+var ErrNoTransaction = errors.New("no transaction has been started")
+
+// lockName is the name of the migration lock, taking the history table's
+// schema and name as parameters. GET_LOCK names are server wide, so the lock
+// is scoped to the history table so that applications sharing a server do not
+// block each other. Names are limited to 64 characters, which a schema
+// qualified table name can exceed, so it is hashed.
+const lockName = "CONCAT('migrator_', SHA1(CONCAT(COALESCE(NULLIF(?, ''), DATABASE()), '.', ?)))"
+
 // NewMySQLDatabaseServicer creates an implementation of the DatabaseServicer
 // for the MySQL database engine.
 func NewMySQLDatabaseServicer(cs string) (migrator.DatabaseServicer, error) {
@@ -18,134 +34,173 @@ func NewMySQLDatabaseServicer(cs string) (migrator.DatabaseServicer, error) {
 		return nil, err
 	}
 
-	return mysql{db: db}, nil
+	return &mysql{db: db, table: migrator.DefaultHistoryTable}, nil
 }
 
+// mysql holds on to the open transaction so that every statement issued
+// between BeginTransaction and CommitTransaction runs on the same pooled
+// connection. Named locks belong to a session, so the migration lock is held
+// on a dedicated connection of its own.
 type mysql struct {
-	db *sql.DB
+	db   *sql.DB
+	tx   *sql.Tx
+	lock *sql.Conn
+
+	// schema and table locate the migration history table; an empty schema
+	// is the connection's current database.
+	schema string
+	table  string
 }
 
-func (m mysql) RunMigration(mi migrator.Migration) error {
-	_, err := m.db.Exec(string(mi.FileContents))
-	if err != nil {
-		return err
+func (m *mysql) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if m.tx != nil {
+		return m.tx.ExecContext(ctx, query, args...)
 	}
 
-	// Add this migration to the history table.
-	return nil
+	return m.db.ExecContext(ctx, query, args...)
 }
 
-func (m mysql) BeginTransaction() error {
-	_, err := m.db.Exec("START TRANSACTION")
-	if err != nil {
-		return err
+func (m *mysql) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if m.tx != nil {
+		return m.tx.QueryContext(ctx, query, args...)
 	}
 
-	return nil
+	return m.db.QueryContext(ctx, query, args...)
 }
 
-func (m mysql) RanMigrations() ([]migrator.RanMigration, error) {
-	var ranMigrations []migrator.RanMigration
+func (m *mysql) AcquireLock(ctx context.Context, timeout time.Duration) (bool, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
 
-	rows, err := m.db.Query(`
-		SELECT id, file_name, ran
-		FROM migration_history
-	`)
+	var result sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK("+lockName+", ?)",
+		m.schema, m.table, int(math.Ceil(timeout.Seconds()))).Scan(&result)
 	if err != nil {
-		return nil, err
+		conn.Close()
+		return false, err
 	}
 
-	for rows.Next() {
-		var rm migrator.RanMigration
+	if !result.Valid || result.Int64 != 1 {
+		conn.Close()
+		return false, nil
+	}
 
-		err = rows.Scan(&rm.ID, &rm.FileName, &rm.Ran)
-		if err != nil {
-			return nil, err
-		}
+	m.lock = conn
+
+	return true, nil
+}
 
-		ranMigrations = append(ranMigrations, rm)
+func (m *mysql) ReleaseLock() error {
+	if m.lock == nil {
+		return nil
 	}
 
-	return ranMigrations, nil
+	conn := m.lock
+	m.lock = nil
+
+	return sqlutil.ReleaseSessionLock(conn, "DO RELEASE_LOCK("+lockName+")", m.schema, m.table)
 }
 
-func (m mysql) RemoveMigrationHistory(mi migrator.Migration) error {
-	_, err := m.db.Exec(`
-		DELETE migration_history
-		FROM migration_history
-		WHERE id = ?`, mi.ID)
-	if err != nil {
-		return err
+// execScript runs each statement of a script individually so that scripts
+// work without multiStatements=true in the DSN and can change the delimiter
+// for stored procedures and triggers.
+func (m *mysql) execScript(ctx context.Context, script []byte) error {
+	for i, st := range splitStatements(string(script)) {
+		_, err := m.exec(ctx, st.sql)
+		if err != nil {
+			return ErrRunningStatement{
+				Index: i + 1,
+				Line:  st.line,
+				Err:   err,
+			}
+		}
 	}
 
 	return nil
 }
 
-func (m mysql) RollbackMigration(mi migrator.Migration) error {
-	_, err := m.db.Exec(string(mi.Rollback.FileContents))
+func (m *mysql) RunMigration(ctx context.Context, mi migrator.Migration) error {
+	if mi.Up != nil {
+		return sqlutil.RunFunc(ctx, m.db, m.tx, mi.Up)
+	}
+
+	return m.execScript(ctx, mi.FileContents)
+}
+
+func (m *mysql) BeginTransaction(ctx context.Context) error {
+	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
+	m.tx = tx
+
 	return nil
 }
 
-func (m mysql) TryCreateHistoryTable() (bool, error) {
-	// See if object already exists.
-	rows, err := m.db.Query("SHOW TABLES LIKE 'migration_history'")
+func (m *mysql) RanMigrations(ctx context.Context) ([]migrator.RanMigration, error) {
+	return m.history().RanMigrations(ctx, m.query)
+}
+
+func (m *mysql) RemoveMigrationHistory(ctx context.Context, mi migrator.Migration) error {
+	_, err := m.exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE id = ?`, m.historyTable()), mi.ID)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	var resultsFound bool
+	return nil
+}
 
-	for rows.Next() {
-		resultsFound = true
-		break
+func (m *mysql) RollbackMigration(ctx context.Context, mi migrator.Migration) error {
+	if mi.Rollback.Down != nil {
+		return sqlutil.RunFunc(ctx, m.db, m.tx, mi.Rollback.Down)
 	}
 
-	if resultsFound {
-		return false, nil
-	}
+	return m.execScript(ctx, mi.Rollback.FileContents)
+}
 
-	// It obviously doesn't - needs creating.
-	_, err = m.db.Exec(`
-		CREATE TABLE migration_history
-		(
-			id		 INT NOT NULL,
-			file_name VARCHAR(255) NOT NULL,
-			ran		 DATETIME NOT NULL
-		)`)
-	if err != nil {
-		return false, err
-	}
+// DefaultTransactionMode is one transaction per migration as MySQL implicitly
+// commits on DDL; a single transaction for the whole run would leave earlier
+// migrations applied but possibly not recorded when a later one fails.
+func (m *mysql) DefaultTransactionMode() migrator.TransactionMode {
+	return migrator.TransactionModePerMigration
+}
 
-	return true, nil
+func (m *mysql) HistoryTableExists(ctx context.Context) (bool, error) {
+	return m.tableExists(ctx, m.table)
 }
 
-func (m mysql) CommitTransaction() error {
-	_, err := m.db.Exec("COMMIT")
-	if err != nil {
-		return err
+func (m *mysql) CommitTransaction() error {
+	if m.tx == nil {
+		return ErrNoTransaction
 	}
 
-	return nil
+	err := m.tx.Commit()
+	m.tx = nil
+
+	return err
 }
 
-func (m mysql) RollbackTransaction() error {
-	_, err := m.db.Exec("ROLLBACK")
-	if err != nil {
-		return err
+func (m *mysql) RollbackTransaction() error {
+	if m.tx == nil {
+		return nil
 	}
 
-	return nil
+	err := m.tx.Rollback()
+	m.tx = nil
+
+	return err
 }
 
-func (m mysql) WriteMigrationHistory(mi migrator.Migration) error {
-	_, err := m.db.Exec(`
-		INSERT INTO migration_history (id, file_name, ran)
-		VALUES (?, ?, ?)
-	`, mi.ID, mi.FileName, time.Now())
+func (m *mysql) WriteMigrationHistory(ctx context.Context, mi migrator.Migration, e migrator.Execution) error {
+	_, err := m.exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, file_name, ran, checksum, duration_ms, host, username, migrator_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, m.historyTable()), mi.ID, mi.FileName, time.Now(), mi.Checksum(), e.Duration.Milliseconds(), e.Host, e.User, e.Version)
 	if err != nil {
 		return err
 	}
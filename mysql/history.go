@@ -0,0 +1,193 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bunsenapp/migrator/internal/history"
+)
+
+// SetHistoryTable sets the schema and name of the migration history table.
+func (m *mysql) SetHistoryTable(schema, table string) error {
+	m.schema = schema
+	m.table = table
+
+	return nil
+}
+
+// historyTable returns the quoted, schema qualified name of the history
+// table for use in statements.
+func (m *mysql) historyTable() string {
+	return m.qualify(m.table)
+}
+
+// qualify quotes a table name and prefixes it with the schema, if there is
+// one.
+func (m *mysql) qualify(table string) string {
+	return history.QualifiedName("`", m.schema, table)
+}
+
+// history describes the history table for the shared creation and upgrade
+// handling.
+func (m *mysql) history() history.Table {
+	return history.Table{
+		DB:           m.db,
+		Name:         m.table,
+		Qualify:      m.qualify,
+		TableExists:  m.tableExists,
+		ColumnExists: m.columnExists,
+		Create:       m.createHistoryTable,
+		Upgrades: map[int]history.Upgrade{
+			2: m.widenID,
+			3: m.addPrimaryKey,
+			4: m.addFileNameIndex,
+			5: m.addRanPrecision,
+		},
+	}
+}
+
+func (m *mysql) TryCreateHistoryTable(ctx context.Context) (bool, error) {
+	return m.history().TryCreate(ctx)
+}
+
+// createHistoryTable creates the history table with the layout of
+// history.Version.
+func (m *mysql) createHistoryTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s
+		(
+			id               BIGINT NOT NULL,
+			file_name        VARCHAR(255) NOT NULL,
+			ran              DATETIME(6) NOT NULL,
+			checksum         VARCHAR(64) NULL,
+			duration_ms      BIGINT NULL,
+			host             VARCHAR(255) NULL,
+			username         VARCHAR(255) NULL,
+			migrator_version VARCHAR(64) NULL,
+			PRIMARY KEY (id),
+			INDEX %s (file_name)
+		)`, m.historyTable(), history.QualifiedName("`", "", m.fileNameIndex())))
+
+	return err
+}
+
+// widenID converts the 32 bit id column of tables created before timestamp
+// IDs were supported, which is too small to hold them.
+func (m *mysql) widenID(ctx context.Context) error {
+	idType, err := m.columnType(ctx, "id")
+	if err != nil || !strings.HasPrefix(idType, "int") {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		ALTER TABLE `+m.historyTable()+`
+		MODIFY id BIGINT NOT NULL`)
+
+	return err
+}
+
+// addPrimaryKey makes id the primary key so that a migration cannot be
+// recorded twice. It fails if the table already holds duplicate IDs.
+func (m *mysql) addPrimaryKey(ctx context.Context) error {
+	var count int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM information_schema.table_constraints
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE())
+		AND table_name = ?
+		AND constraint_type = 'PRIMARY KEY'
+	`, m.schema, m.table).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		ALTER TABLE `+m.historyTable()+`
+		ADD PRIMARY KEY (id)`)
+
+	return err
+}
+
+// addFileNameIndex indexes file_name, which rollbacks and the status command
+// look migrations up by.
+func (m *mysql) addFileNameIndex(ctx context.Context) error {
+	var count int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM information_schema.statistics
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE())
+		AND table_name = ?
+		AND index_name = ?
+	`, m.schema, m.table, m.fileNameIndex()).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE INDEX %s
+		ON %s (file_name)`, history.QualifiedName("`", "", m.fileNameIndex()), m.historyTable()))
+
+	return err
+}
+
+// addRanPrecision stores when migrations were ran to the microsecond rather
+// than the second.
+func (m *mysql) addRanPrecision(ctx context.Context) error {
+	ranType, err := m.columnType(ctx, "ran")
+	if err != nil || ranType != "datetime" {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		ALTER TABLE `+m.historyTable()+`
+		MODIFY ran DATETIME(6) NOT NULL`)
+
+	return err
+}
+
+// fileNameIndex is the name of the index on the history table's file_name
+// column.
+func (m *mysql) fileNameIndex() string {
+	return m.table + "_file_name_idx"
+}
+
+// tableExists reports whether a table exists in the history table's schema.
+func (m *mysql) tableExists(ctx context.Context, table string) (bool, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE())
+		AND table_name = ?
+	`, m.schema, table).Scan(&count)
+
+	return count > 0, err
+}
+
+// columnType returns the type of a column of the history table, such as
+// "bigint" or "datetime(6)", or an empty string when the column does not
+// exist.
+func (m *mysql) columnType(ctx context.Context, column string) (string, error) {
+	var columnType string
+	err := m.db.QueryRowContext(ctx, `
+		SELECT column_type
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE())
+		AND table_name = ?
+		AND column_name = ?
+	`, m.schema, m.table, column).Scan(&columnType)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return strings.ToLower(columnType), err
+}
+
+// columnExists reports whether the history table has a column.
+func (m *mysql) columnExists(ctx context.Context, column string) (bool, error) {
+	columnType, err := m.columnType(ctx, column)
+
+	return columnType != "", err
+}
@@ -0,0 +1,206 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultDelimiter is the statement delimiter used until a DELIMITER command
+// changes it.
+const defaultDelimiter = ";"
+
+// statement is a single SQL statement split from a script.
+type statement struct {
+	// sql is the statement without its delimiter.
+	sql string
+
+	// line is the line of the script the statement starts on, starting at 1.
+	line int
+}
+
+// ErrRunningStatement is an error that is raised when a single statement of a
+// migration or rollback script fails.
+type ErrRunningStatement struct {
+	// Index is the position of the statement within the script, starting
+	// at 1.
+	Index int
+
+	// Line is the line of the script the statement starts on.
+	Line int
+
+	// Err is the error returned by the database.
+	Err error
+}
+
+// Error yields the error string for the ErrRunningStatement struct.
+func (e ErrRunningStatement) Error() string {
+	return fmt.Sprintf("statement %d on line %d failed: %s", e.Index, e.Line, e.Err)
+}
+
+// splitStatements splits a script into its individual statements as the
+// mysql command line client would. Delimiters inside quoted strings, quoted
+// identifiers and comments are ignored, and DELIMITER commands at the start
+// of a line change the delimiter for the statements that follow them.
+func splitStatements(script string) []statement {
+	var statements []statement
+	var current strings.Builder
+
+	delimiter := defaultDelimiter
+	line := 1
+	startLine := 0
+	lineStart := true
+
+	emit := func() {
+		if startLine > 0 {
+			statements = append(statements, statement{
+				sql:  strings.TrimSpace(current.String()),
+				line: startLine,
+			})
+		}
+
+		current.Reset()
+		startLine = 0
+	}
+
+	// content marks the current statement as having something other than
+	// whitespace and comments in it.
+	content := func() {
+		if startLine == 0 {
+			startLine = line
+		}
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+
+		// The DELIMITER command is only recognised at the start of a line
+		// between statements.
+		if lineStart && startLine == 0 {
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+
+			fields := strings.Fields(script[i : i+end])
+			if len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+				delimiter = fields[1]
+				current.Reset()
+				i += end
+				continue
+			}
+		}
+
+		lineStart = false
+
+		switch {
+		case c == '\n':
+			current.WriteByte(c)
+			line++
+			lineStart = true
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			current.WriteByte(c)
+			lineStart = startLine == 0 && isLineStart(script, i)
+			i++
+		case strings.HasPrefix(script[i:], delimiter):
+			emit()
+			i += len(delimiter)
+		case c == '\'' || c == '"' || c == '`':
+			content()
+			end := quotedEnd(script, i)
+			line += strings.Count(script[i:end], "\n")
+			current.WriteString(script[i:end])
+			i = end
+		case c == '#' || isDashComment(script, i):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.WriteString(script[i : i+end])
+			i += end
+		case strings.HasPrefix(script[i:], "/*"):
+			// Executable comments and optimizer hints are ran by MySQL, so
+			// a statement made up of one, as mysqldump writes, is kept.
+			if strings.HasPrefix(script[i:], "/*!") || strings.HasPrefix(script[i:], "/*+") {
+				content()
+			}
+
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script)
+			} else {
+				end = i + 2 + end + 2
+			}
+			line += strings.Count(script[i:end], "\n")
+			current.WriteString(script[i:end])
+			i = end
+		default:
+			content()
+			current.WriteByte(c)
+			i++
+		}
+	}
+
+	emit()
+
+	return statements
+}
+
+// quotedEnd returns the index just after the quoted string or identifier that
+// starts at i, honouring backslash escapes and doubled quotes.
+func quotedEnd(script string, i int) int {
+	quote := script[i]
+
+	for j := i + 1; j < len(script); j++ {
+		switch script[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			if j+1 < len(script) && script[j+1] == quote {
+				j++
+				continue
+			}
+
+			return j + 1
+		}
+	}
+
+	return len(script)
+}
+
+// isDashComment reports whether a "--" comment starts at index i. MySQL only
+// treats "--" as a comment when it is followed by whitespace or the end of
+// the script, so that expressions such as a--1 are left alone.
+func isDashComment(script string, i int) bool {
+	if !strings.HasPrefix(script[i:], "--") {
+		return false
+	}
+
+	if i+2 == len(script) {
+		return true
+	}
+
+	switch script[i+2] {
+	case ' ', '\t', '\n', '\r':
+		return true
+	}
+
+	return false
+}
+
+// isLineStart reports whether only whitespace precedes index i on its line.
+func isLineStart(script string, i int) bool {
+	for j := i; j >= 0; j-- {
+		switch script[j] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatementsAreSplitOnTheDelimiter(t *testing.T) {
+	statements := splitStatements("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n")
+
+	expected := []statement{
+		{sql: "CREATE TABLE a (id INT)", line: 1},
+		{sql: "CREATE TABLE b (id INT)", line: 2},
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("statements were not correct: %#v", statements)
+	}
+}
+
+func TestDelimitersInQuotesAndCommentsAreIgnored(t *testing.T) {
+	script := `-- first; comment
+# second; comment
+/* block;
+   comment */
+INSERT INTO a VALUES ('it''s;', "quoted;\"", 'escaped\';');
+SELECT ` + "`odd;name`" + ` FROM a;`
+
+	statements := splitStatements(script)
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %#v", len(statements), statements)
+	}
+
+	if statements[0].line != 5 || statements[1].line != 6 {
+		t.Errorf("line numbers were not correct: %#v", statements)
+	}
+	if statements[1].sql != "SELECT `odd;name` FROM a" {
+		t.Errorf("statement was not correct: %s", statements[1].sql)
+	}
+}
+
+func TestDoubleDashIsOnlyACommentWhenFollowedByWhitespace(t *testing.T) {
+	statements := splitStatements("UPDATE t SET a = a--1;\nSELECT 2; --\nSELECT 3;--")
+
+	expected := []statement{
+		{sql: "UPDATE t SET a = a--1", line: 1},
+		{sql: "SELECT 2", line: 2},
+		{sql: "--\nSELECT 3", line: 3},
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("statements were not correct: %#v", statements)
+	}
+}
+
+func TestExecutableCommentsAreKeptAsStatements(t *testing.T) {
+	statements := splitStatements("/*!40101 SET NAMES utf8 */;\n/* plain; comment */\n/*+ SET_VAR(sort_buffer_size = 16M) */;\nSELECT 1;")
+
+	expected := []statement{
+		{sql: "/*!40101 SET NAMES utf8 */", line: 1},
+		{sql: "/* plain; comment */\n/*+ SET_VAR(sort_buffer_size = 16M) */", line: 3},
+		{sql: "SELECT 1", line: 4},
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("statements were not correct: %#v", statements)
+	}
+}
+
+func TestDelimiterCommandChangesTheDelimiter(t *testing.T) {
+	script := `DROP PROCEDURE IF EXISTS p;
+
+DELIMITER $$
+CREATE PROCEDURE p()
+BEGIN
+	SELECT 1;
+	SELECT 2;
+END$$
+delimiter ;
+
+CALL p();
+`
+
+	statements := splitStatements(script)
+
+	expected := []statement{
+		{sql: "DROP PROCEDURE IF EXISTS p", line: 1},
+		{sql: "CREATE PROCEDURE p()\nBEGIN\n\tSELECT 1;\n\tSELECT 2;\nEND", line: 4},
+		{sql: "CALL p()", line: 11},
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("statements were not correct: %#v", statements)
+	}
+}
+
+func TestTrailingStatementWithoutADelimiterIsIncluded(t *testing.T) {
+	statements := splitStatements("SELECT 1;\n\nSELECT 2\n-- trailing comment\n")
+
+	if len(statements) != 2 || statements[1].sql != "SELECT 2\n-- trailing comment" || statements[1].line != 3 {
+		t.Errorf("statements were not correct: %#v", statements)
+	}
+}
@@ -0,0 +1,71 @@
+package migrator
+
+// OutOfOrderPolicy is how pending migrations with an ID lower than the
+// highest applied migration are treated. These usually come from a branch
+// that was merged after later migrations had already been deployed.
+type OutOfOrderPolicy int
+
+const (
+	// OutOfOrderAllow applies out of order migrations without comment. This
+	// is the default.
+	OutOfOrderAllow OutOfOrderPolicy = iota
+
+	// OutOfOrderWarn logs out of order migrations and applies them.
+	OutOfOrderWarn
+
+	// OutOfOrderReject refuses to run, failing with ErrOutOfOrderMigrations
+	// and listing the migrations that are out of order.
+	OutOfOrderReject
+)
+
+// outOfOrderMigrations returns the pending migrations that have an ID lower
+// than the highest ID that has been ran.
+func outOfOrderMigrations(pending []Migration, r []RanMigration) []Migration {
+	if len(r) == 0 {
+		return nil
+	}
+
+	highest := r[0].ID
+	for _, ranMigration := range r[1:] {
+		if ranMigration.ID > highest {
+			highest = ranMigration.ID
+		}
+	}
+
+	var outOfOrder []Migration
+	for _, m := range pending {
+		if m.ID < highest {
+			outOfOrder = append(outOfOrder, m)
+		}
+	}
+
+	return outOfOrder
+}
+
+// checkOutOfOrder applies the configured OutOfOrderPolicy to the migrations
+// about to be ran.
+func (m Migrator) checkOutOfOrder(pending []Migration, r []RanMigration) error {
+	outOfOrder := outOfOrderMigrations(pending, r)
+	if len(outOfOrder) == 0 {
+		return nil
+	}
+
+	files := make([]string, 0, len(outOfOrder))
+	for _, migration := range outOfOrder {
+		files = append(files, migration.FileName)
+	}
+
+	switch m.Config.OutOfOrderPolicy {
+	case OutOfOrderReject:
+		return NewErrOutOfOrderMigrations(files)
+	case OutOfOrderWarn:
+		for _, migration := range outOfOrder {
+			m.logger().Warn("migration is older than the latest ran migration",
+				LogKeyPhase, phaseMigrate,
+				LogKeyMigrationID, migration.ID,
+				LogKeyFileName, migration.FileName)
+		}
+	}
+
+	return nil
+}
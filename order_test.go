@@ -0,0 +1,123 @@
+package migrator_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+// outOfOrderMigration creates migration 2 and reports migration 3 as the
+// only one ran, leaving migrations 1 and 2 out of order.
+func outOfOrderMigration(config migrator.Configuration) (mock.MockDatabaseServicer, *[]string) {
+	os.Create(fmt.Sprintf("%s/2_migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/2_migration_down.sql", config.RollbacksDir))
+	os.Create(fmt.Sprintf("%s/3_migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/3_migration_down.sql", config.RollbacksDir))
+
+	var ran []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       3,
+				FileName: "3_migration_up.sql",
+			},
+		}, nil
+	}
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		ran = append(ran, m.FileName)
+		return nil
+	}
+
+	return db, &ran
+}
+
+func TestOutOfOrderMigrationsAreAppliedByDefault(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	db, ran := outOfOrderMigration(config)
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(*ran) != 2 {
+		t.Errorf("out of order migrations were not applied: %v", *ran)
+	}
+}
+
+func TestOutOfOrderMigrationsAreLoggedAndAppliedWhenConfigured(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+	config.OutOfOrderPolicy = migrator.OutOfOrderWarn
+
+	db, ran := outOfOrderMigration(config)
+
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(config, db, log.New(&buf, "", 0))
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(*ran) != 2 {
+		t.Errorf("out of order migrations were not applied: %v", *ran)
+	}
+
+	if !strings.Contains(buf.String(), "WARN migration is older than the latest ran migration phase=migrate migration_id=2") {
+		t.Errorf("out of order migrations were not logged: %s", buf.String())
+	}
+}
+
+func TestOutOfOrderMigrationsAreRejectedWhenConfigured(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+	config.OutOfOrderPolicy = migrator.OutOfOrderReject
+
+	db, ran := outOfOrderMigration(config)
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	err := m.Migrate()
+
+	rejected, ok := err.(migrator.ErrOutOfOrderMigrations)
+	if !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if len(rejected.Files) != 2 {
+		t.Errorf("out of order migrations were not listed: %v", rejected.Files)
+	}
+
+	if len(*ran) != 0 {
+		t.Errorf("migrations were ran when they shouldn't have been: %v", *ran)
+	}
+}
+
+func TestStatusMarksOutOfOrderMigrations(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+	config.OutOfOrderPolicy = migrator.OutOfOrderReject
+
+	db, _ := outOfOrderMigration(config)
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	for _, s := range statuses {
+		if s.OutOfOrder != (s.ID < 3) {
+			t.Errorf("migration %d was not marked correctly: %+v", s.ID, s)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package migrator
+
+import (
+	"context"
+	"sort"
+)
+
+// planMigrate logs every migration that would be ran and its contents, in
+// order, without writing to the database.
+func (m Migrator) planMigrate(ctx context.Context, selectMigrations migrationSelector) error {
+	migrationFiles, ranMigrations, err := m.readMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending, err := selectMigrations(migrationFiles, ranMigrations)
+	if err != nil {
+		return err
+	}
+
+	if err = m.checkOutOfOrder(pending, ranMigrations); err != nil {
+		return err
+	}
+	m.logger().Info("dry run: migrations would be ran",
+		LogKeyPhase, phasePlan,
+		"migrations", len(pending))
+
+	for _, migration := range pending {
+		m.logger().Info("dry run: would migrate",
+			LogKeyPhase, phasePlan,
+			LogKeyMigrationID, migration.ID,
+			LogKeyFileName, migration.FileName,
+			"sql", logText(migration.FileContents))
+	}
+
+	return nil
+}
+
+// planRollback logs the rollbacks that would be ran, in order, without
+// writing to the database.
+func (m Migrator) planRollback(ctx context.Context, selectRollbacks rollbackSelector) error {
+	migrationFiles, ranMigrations, err := m.readMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	toRollback, err := selectRollbacks(migrationFiles, ranMigrations)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range toRollback {
+		m.logger().Info("dry run: would roll back",
+			LogKeyPhase, phasePlan,
+			LogKeyMigrationID, migration.ID,
+			LogKeyFileName, migration.FileName,
+			"rollback_file_name", migration.Rollback.FileName,
+			"sql", logText(migration.Rollback.FileContents))
+	}
+
+	return nil
+}
+
+// readMigrations reads the migration files and history without taking the
+// migration lock, opening a transaction or creating the history table.
+func (m Migrator) readMigrations(ctx context.Context) ([]Migration, []RanMigration, error) {
+	if err := m.Config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if m.DatabaseServicer == nil {
+		return nil, nil, ErrDbServicerNotInitialised
+	}
+
+	if err := m.configureHistoryTable(); err != nil {
+		return nil, nil, err
+	}
+
+	migrationFiles, err := m.findMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Sort(migrations(migrationFiles))
+
+	exists, err := m.DatabaseServicer.HistoryTableExists(ctx)
+	if err != nil {
+		return nil, nil, ErrUnableToRetrieveRanMigrations
+	}
+
+	// Without a history table nothing has been ran yet.
+	if !exists {
+		return migrationFiles, nil, nil
+	}
+
+	ranMigrations, err := m.DatabaseServicer.RanMigrations(ctx)
+	if err != nil {
+		return nil, nil, ErrUnableToRetrieveRanMigrations
+	}
+
+	if err = m.verifyChecksums(migrationFiles, ranMigrations); err != nil {
+		return nil, nil, err
+	}
+
+	return migrationFiles, ranMigrations, nil
+}
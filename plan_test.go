@@ -0,0 +1,173 @@
+package migrator_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+// writingDatabaseServicer returns a mock database servicer that flags any
+// call that would write to the database.
+func writingDatabaseServicer(written *bool) mock.MockDatabaseServicer {
+	db := mock.WorkingMockDatabaseServicer()
+	db.AcquireLockFunc = func(timeout time.Duration) (bool, error) {
+		*written = true
+		return true, nil
+	}
+	db.TryCreateHistoryTableFunc = func() (bool, error) {
+		*written = true
+		return true, nil
+	}
+	db.BeginTransactionFunc = func() error {
+		*written = true
+		return nil
+	}
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		*written = true
+		return nil
+	}
+	db.WriteMigrationHistoryFunc = func(m migrator.Migration, e migrator.Execution) error {
+		*written = true
+		return nil
+	}
+	db.RollbackMigrationFunc = func(m migrator.Migration) error {
+		*written = true
+		return nil
+	}
+	db.RemoveMigrationHistoryFunc = func(m migrator.Migration) error {
+		*written = true
+		return nil
+	}
+
+	return db
+}
+
+func TestDryRunMigrateLogsPendingMigrationsWithoutWriting(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.DryRun = true
+	ioutil.WriteFile(fmt.Sprintf("%s/2_second-migration_up.sql", config.MigrationsDir), []byte("CREATE TABLE foo;"), 0600)
+	ioutil.WriteFile(fmt.Sprintf("%s/2_second-migration_down.sql", config.RollbacksDir), []byte("DROP TABLE foo;"), 0600)
+
+	written := false
+	db := writingDatabaseServicer(&written)
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       1,
+				FileName: "1_first-migration_up.sql",
+			},
+		}, nil
+	}
+
+	var buf bytes.Buffer
+	m := NewConfiguredMigrator(config, db, log.New(&buf, "", 0))
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if written {
+		t.Errorf("database was written to during a dry run")
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "file_name=1_first-migration_up.sql") {
+		t.Errorf("already ran migration was included in the plan")
+	}
+	if !strings.Contains(output, "would migrate phase=plan migration_id=2 file_name=2_second-migration_up.sql\nCREATE TABLE foo;\n") {
+		t.Errorf("pending migration was not included in the plan: %s", output)
+	}
+}
+
+func TestDryRunDoesNotReadHistoryWhenTableDoesNotExist(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.DryRun = true
+
+	written := false
+	historyRead := false
+	db := writingDatabaseServicer(&written)
+	db.HistoryTableExistsFunc = func() (bool, error) {
+		return false, nil
+	}
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		historyRead = true
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	m := NewConfiguredMigrator(config, db, log.New(&buf, "", 0))
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if written || historyRead {
+		t.Errorf("history table was used when it does not exist")
+	}
+	if !strings.Contains(buf.String(), "would migrate phase=plan migration_id=1 file_name=1_first-migration_up.sql") {
+		t.Errorf("pending migration was not included in the plan")
+	}
+}
+
+func TestDryRunRollbackLogsRollbackWithoutWriting(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.DryRun = true
+
+	written := false
+	db := writingDatabaseServicer(&written)
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID: 1,
+			},
+		}, nil
+	}
+
+	var buf bytes.Buffer
+	m := NewConfiguredMigrator(config, db, log.New(&buf, "", 0))
+	if err := m.Rollback("1_first-migration_up.sql"); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if written {
+		t.Errorf("database was written to during a dry run")
+	}
+	if !strings.Contains(buf.String(), "file_name=1_first-migration_up.sql rollback_file_name=1_first-migration_down.sql") {
+		t.Errorf("rollback was not included in the plan")
+	}
+}
+
+func TestDryRunRollbackOfANotLatestMigrationResultsInAnError(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	config.DryRun = true
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID: 1,
+			},
+			{
+				ID: 2,
+			},
+		}, nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Rollback("1_first-migration_up.sql"); err != migrator.ErrNotLatestMigration {
+		t.Errorf("error was not returned when it should have been")
+	}
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/internal/sqlutil"
+
+	// Import the required PostgreSQL driver.
+	_ "github.com/lib/pq"
+)
+
+// ErrNoTransaction is an error that is raised when a transaction is committed
+// or rolled back without one having been started.
+var ErrNoTransaction = errors.New("no transaction has been started")
+
+// lockPollInterval is how often an advisory lock held by another session is
+// retried whilst waiting for it.
+const lockPollInterval = 250 * time.Millisecond
+
+// lockKey derives the advisory lock key from the history table's schema and
+// name, given as $1 and $2, so that applications sharing a database do not
+// block each other.
+const lockKey = "hashtext(COALESCE(NULLIF($1, ''), current_schema()) || '.' || $2)"
+
+// NewPostgreSQLDatabaseServicer creates an implementation of the
+// DatabaseServicer for the PostgreSQL database engine.
+func NewPostgreSQLDatabaseServicer(cs string) (migrator.DatabaseServicer, error) {
+	db, err := sql.Open("postgres", cs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgres{db: db, table: migrator.DefaultHistoryTable}, nil
+}
+
+// postgres holds on to the open transaction so that every statement in a run
+// is executed on the same connection; PostgreSQL supports transactional DDL so
+// this is what makes a failed run leave the schema untouched. Advisory locks
+// belong to a session, so the migration lock is held on a dedicated
+// connection of its own.
+type postgres struct {
+	db   *sql.DB
+	tx   *sql.Tx
+	lock *sql.Conn
+
+	// schema and table locate the migration history table; an empty schema
+	// is the connection's current schema.
+	schema string
+	table  string
+}
+
+func (p *postgres) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if p.tx != nil {
+		return p.tx.ExecContext(ctx, query, args...)
+	}
+
+	return p.db.ExecContext(ctx, query, args...)
+}
+
+func (p *postgres) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if p.tx != nil {
+		return p.tx.QueryContext(ctx, query, args...)
+	}
+
+	return p.db.QueryContext(ctx, query, args...)
+}
+
+func (p *postgres) AcquireLock(ctx context.Context, timeout time.Duration) (bool, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		var locked bool
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock("+lockKey+")", p.schema, p.table).Scan(&locked)
+		if err != nil {
+			conn.Close()
+			return false, err
+		}
+
+		if locked {
+			p.lock = conn
+			return true, nil
+		}
+
+		if time.Now().After(deadline) {
+			conn.Close()
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return false, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func (p *postgres) ReleaseLock() error {
+	if p.lock == nil {
+		return nil
+	}
+
+	conn := p.lock
+	p.lock = nil
+
+	return sqlutil.ReleaseSessionLock(conn, "SELECT pg_advisory_unlock("+lockKey+")", p.schema, p.table)
+}
+
+func (p *postgres) RunMigration(ctx context.Context, mi migrator.Migration) error {
+	if mi.Up != nil {
+		return sqlutil.RunFunc(ctx, p.db, p.tx, mi.Up)
+	}
+
+	_, err := p.exec(ctx, string(mi.FileContents))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *postgres) BeginTransaction(ctx context.Context) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	p.tx = tx
+
+	return nil
+}
+
+func (p *postgres) RanMigrations(ctx context.Context) ([]migrator.RanMigration, error) {
+	return p.history().RanMigrations(ctx, p.query)
+}
+
+func (p *postgres) RemoveMigrationHistory(ctx context.Context, mi migrator.Migration) error {
+	_, err := p.exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE id = $1`, p.historyTable()), mi.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *postgres) RollbackMigration(ctx context.Context, mi migrator.Migration) error {
+	if mi.Rollback.Down != nil {
+		return sqlutil.RunFunc(ctx, p.db, p.tx, mi.Rollback.Down)
+	}
+
+	_, err := p.exec(ctx, string(mi.Rollback.FileContents))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DefaultTransactionMode is a single transaction for the whole run as
+// PostgreSQL supports transactional DDL.
+func (p *postgres) DefaultTransactionMode() migrator.TransactionMode {
+	return migrator.TransactionModeAll
+}
+
+func (p *postgres) HistoryTableExists(ctx context.Context) (bool, error) {
+	return p.tableExists(ctx, p.table)
+}
+
+func (p *postgres) CommitTransaction() error {
+	if p.tx == nil {
+		return ErrNoTransaction
+	}
+
+	err := p.tx.Commit()
+	p.tx = nil
+
+	return err
+}
+
+func (p *postgres) RollbackTransaction() error {
+	if p.tx == nil {
+		return nil
+	}
+
+	err := p.tx.Rollback()
+	p.tx = nil
+
+	return err
+}
+
+func (p *postgres) WriteMigrationHistory(ctx context.Context, mi migrator.Migration, e migrator.Execution) error {
+	_, err := p.exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, file_name, ran, checksum, duration_ms, host, username, migrator_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, p.historyTable()), mi.ID, mi.FileName, time.Now(), mi.Checksum(), e.Duration.Milliseconds(), e.Host, e.User, e.Version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,303 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/internal/history"
+)
+
+// recorder is a database/sql driver that records the statements it is given
+// and answers queries from a function, so that the SQL the servicer generates
+// can be checked without a PostgreSQL server.
+type recorder struct {
+	mu         sync.Mutex
+	statements []recorded
+
+	// respond returns the single column rows for a query.
+	respond func(query string, args []interface{}) []driver.Value
+}
+
+// recorded is a statement along with its arguments, with the whitespace of
+// the statement collapsed.
+type recorded struct {
+	sql  string
+	args []interface{}
+}
+
+var (
+	recordersMu sync.Mutex
+	recorders   = map[string]*recorder{}
+)
+
+func init() {
+	sql.Register("postgres-recorder", recordingDriver{})
+}
+
+type recordingDriver struct{}
+
+func (recordingDriver) Open(name string) (driver.Conn, error) {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	return recordingConn{r: recorders[name]}, nil
+}
+
+type recordingConn struct {
+	r *recorder
+}
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c recordingConn) Close() error { return nil }
+
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+func (c recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.record(query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (c recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	statement, values := c.r.record(query, args)
+
+	var rows []driver.Value
+	if c.r.respond != nil {
+		rows = c.r.respond(statement, values)
+	}
+
+	return &recordedRows{rows: rows}, nil
+}
+
+func (r *recorder) record(query string, args []driver.NamedValue) (string, []interface{}) {
+	statement := strings.Join(strings.Fields(query), " ")
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	r.mu.Lock()
+	r.statements = append(r.statements, recorded{sql: statement, args: values})
+	r.mu.Unlock()
+
+	return statement, values
+}
+
+// executed returns the statements that change the database, in order.
+func (r *recorder) executed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var statements []string
+	for _, s := range r.statements {
+		if !strings.HasPrefix(s.sql, "SELECT") {
+			statements = append(statements, s.sql)
+		}
+	}
+
+	return statements
+}
+
+// find returns the first recorded statement starting with prefix.
+func (r *recorder) find(prefix string) (recorded, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.statements {
+		if strings.HasPrefix(s.sql, prefix) {
+			return s, true
+		}
+	}
+
+	return recorded{}, false
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type recordedRows struct {
+	rows []driver.Value
+}
+
+func (r *recordedRows) Columns() []string { return []string{"value"} }
+func (r *recordedRows) Close() error      { return nil }
+
+func (r *recordedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	dest[0] = r.rows[0]
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+// recordingServicer creates a servicer whose statements are recorded.
+func recordingServicer(t *testing.T, respond func(query string, args []interface{}) []driver.Value) (*postgres, *recorder) {
+	r := &recorder{respond: respond}
+
+	recordersMu.Lock()
+	recorders[t.Name()] = r
+	recordersMu.Unlock()
+
+	db, err := sql.Open("postgres-recorder", t.Name())
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+
+	return &postgres{db: db, table: migrator.DefaultHistoryTable}, r
+}
+
+// legacyHistoryTable answers queries as a database holding a history table
+// created by the first release would: a 32 bit id, none of the columns added
+// since, no primary key and no version table.
+func legacyHistoryTable(query string, args []interface{}) []driver.Value {
+	switch {
+	case strings.Contains(query, "FROM information_schema.tables"):
+		if args[1] == migrator.DefaultHistoryTable {
+			return []driver.Value{int64(1)}
+		}
+
+		return []driver.Value{int64(0)}
+	case strings.Contains(query, "FROM information_schema.columns"):
+		if args[2] == "id" {
+			return []driver.Value{"integer"}
+		}
+
+		return nil
+	case strings.HasPrefix(query, "SELECT COUNT(*)"):
+		return []driver.Value{int64(0)}
+	}
+
+	return nil
+}
+
+func TestMigrationLockIsScopedToTheHistoryTable(t *testing.T) {
+	p, r := recordingServicer(t, func(query string, args []interface{}) []driver.Value {
+		return []driver.Value{true}
+	})
+	p.SetHistoryTable("ops", "billing_migrations")
+
+	if locked, err := p.AcquireLock(context.Background(), 0); err != nil || !locked {
+		t.Fatalf("lock was not acquired: %v", err)
+	}
+
+	if err := p.ReleaseLock(); err != nil {
+		t.Fatalf("error releasing lock: %s", err)
+	}
+
+	key := "hashtext(COALESCE(NULLIF($1, ''), current_schema()) || '.' || $2)"
+	args := []interface{}{"ops", "billing_migrations"}
+
+	for _, expected := range []string{"SELECT pg_try_advisory_lock(" + key + ")", "SELECT pg_advisory_unlock(" + key + ")"} {
+		s, ok := r.find(expected)
+		if !ok {
+			t.Errorf("expected %s to be ran, got %+v", expected, r.statements)
+			continue
+		}
+
+		if !reflect.DeepEqual(s.args, args) {
+			t.Errorf("expected %s to be given %v, got %v", expected, args, s.args)
+		}
+	}
+}
+
+func TestHistoryTableIsCreatedWithTheLatestLayout(t *testing.T) {
+	p, r := recordingServicer(t, func(query string, args []interface{}) []driver.Value {
+		return []driver.Value{int64(0)}
+	})
+	p.SetHistoryTable("ops", "billing_migrations")
+
+	created, err := p.TryCreateHistoryTable(context.Background())
+	if err != nil || !created {
+		t.Fatalf("history table was not created: %v", err)
+	}
+
+	expected := []string{
+		`CREATE TABLE "ops"."billing_migrations" ( id BIGINT NOT NULL PRIMARY KEY, file_name VARCHAR(255) NOT NULL, ran TIMESTAMP NOT NULL, checksum VARCHAR(64) NULL, duration_ms BIGINT NULL, host VARCHAR(255) NULL, username VARCHAR(255) NULL, migrator_version VARCHAR(64) NULL )`,
+		`CREATE INDEX IF NOT EXISTS "billing_migrations_file_name_idx" ON "ops"."billing_migrations" (file_name)`,
+		`CREATE TABLE IF NOT EXISTS "ops"."billing_migrations_version" (version INTEGER NOT NULL)`,
+		`DELETE FROM "ops"."billing_migrations_version"`,
+		fmt.Sprintf(`INSERT INTO "ops"."billing_migrations_version" (version) VALUES (%d)`, history.Version),
+	}
+	if executed := r.executed(); !reflect.DeepEqual(executed, expected) {
+		t.Errorf("statements were not correct:\n%s", strings.Join(executed, "\n"))
+	}
+}
+
+func TestLegacyHistoryTableIsUpgradedInPlace(t *testing.T) {
+	p, r := recordingServicer(t, legacyHistoryTable)
+
+	created, err := p.TryCreateHistoryTable(context.Background())
+	if err != nil || created {
+		t.Fatalf("history table was not upgraded: %v", err)
+	}
+
+	expected := []string{
+		`ALTER TABLE "migration_history" ADD COLUMN checksum VARCHAR(64) NULL`,
+		`ALTER TABLE "migration_history" ADD COLUMN duration_ms BIGINT NULL`,
+		`ALTER TABLE "migration_history" ADD COLUMN host VARCHAR(255) NULL`,
+		`ALTER TABLE "migration_history" ADD COLUMN username VARCHAR(255) NULL`,
+		`ALTER TABLE "migration_history" ADD COLUMN migrator_version VARCHAR(64) NULL`,
+		`ALTER TABLE "migration_history" ALTER COLUMN id TYPE BIGINT`,
+		`ALTER TABLE "migration_history" ADD PRIMARY KEY (id)`,
+		`CREATE INDEX IF NOT EXISTS "migration_history_file_name_idx" ON "migration_history" (file_name)`,
+		`CREATE TABLE IF NOT EXISTS "migration_history_version" (version INTEGER NOT NULL)`,
+		`DELETE FROM "migration_history_version"`,
+		fmt.Sprintf(`INSERT INTO "migration_history_version" (version) VALUES (%d)`, history.Version),
+	}
+	if executed := r.executed(); !reflect.DeepEqual(executed, expected) {
+		t.Errorf("statements were not correct:\n%s", strings.Join(executed, "\n"))
+	}
+}
+
+func TestLegacyHistoryTableIsReadBeforeItIsUpgraded(t *testing.T) {
+	p, r := recordingServicer(t, legacyHistoryTable)
+
+	if _, err := p.RanMigrations(context.Background()); err != nil {
+		t.Fatalf("error retrieving ran migrations: %s", err)
+	}
+
+	expected := `SELECT id, file_name, ran, NULL AS checksum, NULL AS duration_ms, NULL AS host, NULL AS username, NULL AS migrator_version FROM "migration_history"`
+	if _, ok := r.find(expected); !ok {
+		t.Errorf("expected %s to be ran, got %+v", expected, r.statements)
+	}
+}
+
+func TestMigrationHistoryIsWrittenWithNumberedPlaceholders(t *testing.T) {
+	p, r := recordingServicer(t, nil)
+
+	m := migrator.Migration{ID: 1, FileName: "1_create-users_up.sql", FileContents: []byte("CREATE TABLE users (id INT);")}
+	if err := p.WriteMigrationHistory(context.Background(), m, migrator.Execution{Host: "build-1"}); err != nil {
+		t.Fatalf("error writing migration history: %s", err)
+	}
+
+	s, ok := r.find("INSERT INTO")
+	if !ok {
+		t.Fatalf("migration history was not written")
+	}
+
+	expected := `INSERT INTO "migration_history" (id, file_name, ran, checksum, duration_ms, host, username, migrator_version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if s.sql != expected || len(s.args) != 8 {
+		t.Errorf("statement was not correct: %s %v", s.sql, s.args)
+	}
+
+	if s.args[0] != int64(1) || s.args[1] != "1_create-users_up.sql" || s.args[3] != m.Checksum() || s.args[5] != "build-1" {
+		t.Errorf("arguments were not correct: %v", s.args)
+	}
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bunsenapp/migrator/internal/history"
+)
+
+// SetHistoryTable sets the schema and name of the migration history table.
+func (p *postgres) SetHistoryTable(schema, table string) error {
+	p.schema = schema
+	p.table = table
+
+	return nil
+}
+
+// historyTable returns the quoted, schema qualified name of the history
+// table for use in statements.
+func (p *postgres) historyTable() string {
+	return p.qualify(p.table)
+}
+
+// qualify quotes a table name and prefixes it with the schema, if there is
+// one.
+func (p *postgres) qualify(table string) string {
+	return history.QualifiedName(`"`, p.schema, table)
+}
+
+// history describes the history table for the shared creation and upgrade
+// handling. TIMESTAMP columns already hold microseconds, so there is nothing
+// to do for version 5.
+func (p *postgres) history() history.Table {
+	return history.Table{
+		DB:           p.db,
+		Name:         p.table,
+		Qualify:      p.qualify,
+		TableExists:  p.tableExists,
+		ColumnExists: p.columnExists,
+		Create:       p.createHistoryTable,
+		Upgrades: map[int]history.Upgrade{
+			2: p.widenID,
+			3: p.addPrimaryKey,
+			4: p.addFileNameIndex,
+		},
+	}
+}
+
+func (p *postgres) TryCreateHistoryTable(ctx context.Context) (bool, error) {
+	return p.history().TryCreate(ctx)
+}
+
+// createHistoryTable creates the history table with the layout of
+// history.Version.
+func (p *postgres) createHistoryTable(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, `
+		CREATE TABLE `+p.historyTable()+`
+		(
+			id               BIGINT NOT NULL PRIMARY KEY,
+			file_name        VARCHAR(255) NOT NULL,
+			ran              TIMESTAMP NOT NULL,
+			checksum         VARCHAR(64) NULL,
+			duration_ms      BIGINT NULL,
+			host             VARCHAR(255) NULL,
+			username         VARCHAR(255) NULL,
+			migrator_version VARCHAR(64) NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	return p.addFileNameIndex(ctx)
+}
+
+// widenID converts the 32 bit id column of tables created before timestamp
+// IDs were supported, which is too small to hold them.
+func (p *postgres) widenID(ctx context.Context) error {
+	idType, err := p.columnType(ctx, "id")
+	if err != nil || idType != "integer" {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `
+		ALTER TABLE `+p.historyTable()+`
+		ALTER COLUMN id TYPE BIGINT`)
+
+	return err
+}
+
+// addPrimaryKey makes id the primary key so that a migration cannot be
+// recorded twice. It fails if the table already holds duplicate IDs.
+func (p *postgres) addPrimaryKey(ctx context.Context) error {
+	var count int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM information_schema.table_constraints
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema())
+		AND table_name = $2
+		AND constraint_type = 'PRIMARY KEY'
+	`, p.schema, p.table).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `
+		ALTER TABLE `+p.historyTable()+`
+		ADD PRIMARY KEY (id)`)
+
+	return err
+}
+
+// addFileNameIndex indexes file_name, which rollbacks and the status command
+// look migrations up by.
+func (p *postgres) addFileNameIndex(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s
+		ON %s (file_name)`, history.QualifiedName(`"`, "", p.table+"_file_name_idx"), p.historyTable()))
+
+	return err
+}
+
+// tableExists reports whether a table exists in the history table's schema.
+func (p *postgres) tableExists(ctx context.Context, table string) (bool, error) {
+	var count int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema())
+		AND table_name = $2
+	`, p.schema, table).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// columnType returns the data type of a column of the history table, or an
+// empty string when the column does not exist.
+func (p *postgres) columnType(ctx context.Context, column string) (string, error) {
+	var dataType string
+	err := p.db.QueryRowContext(ctx, `
+		SELECT data_type
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema())
+		AND table_name = $2
+		AND column_name = $3
+	`, p.schema, p.table, column).Scan(&dataType)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return strings.ToLower(dataType), err
+}
+
+// columnExists reports whether the history table has a column.
+func (p *postgres) columnExists(ctx context.Context, column string) (bool, error) {
+	columnType, err := p.columnType(ctx, column)
+
+	return columnType != "", err
+}
@@ -0,0 +1,203 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/internal/history"
+	"github.com/bunsenapp/migrator/mock"
+	"github.com/bunsenapp/migrator/postgres"
+)
+
+// integrationDatabase returns a configuration pointing at the PostgreSQL
+// database in POSTGRES_DSN, skipping the test when it is not set. Each test
+// gets a history table and migration directories of its own, which are
+// removed by the clean up function along with the given tables.
+func integrationDatabase(t *testing.T, tables ...string) (migrator.Configuration, func()) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	dir, err := ioutil.TempDir("", "migrator-postgres")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: %s", err)
+	}
+
+	config := migrator.Configuration{
+		DatabaseConnectionString: dsn,
+		MigrationsDir:            filepath.Join(dir, "up"),
+		RollbacksDir:             filepath.Join(dir, "down"),
+		HistoryTable:             fmt.Sprintf("migrator_test_%d", time.Now().UnixNano()),
+	}
+
+	os.Mkdir(config.MigrationsDir, 0700)
+	os.Mkdir(config.RollbacksDir, 0700)
+
+	tables = append(tables, config.HistoryTable, config.HistoryTable+"_version")
+
+	return config, func() {
+		os.RemoveAll(dir)
+
+		db := openDatabase(t, config)
+		defer db.Close()
+
+		for _, table := range tables {
+			db.Exec("DROP TABLE IF EXISTS " + table)
+		}
+	}
+}
+
+func openDatabase(t *testing.T, c migrator.Configuration) *sql.DB {
+	db, err := sql.Open("postgres", c.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+
+	return db
+}
+
+// historyTableSetter is implemented by servicers whose history table can be
+// configured.
+type historyTableSetter interface {
+	SetHistoryTable(schema, table string) error
+}
+
+func newServicer(t *testing.T, c migrator.Configuration) migrator.DatabaseServicer {
+	db, err := postgres.NewPostgreSQLDatabaseServicer(c.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if err := db.(historyTableSetter).SetHistoryTable("", c.HistoryTable); err != nil {
+		t.Fatalf("error setting history table: %s", err)
+	}
+
+	return db
+}
+
+func writeMigration(t *testing.T, c migrator.Configuration, name, up, down string) {
+	err := ioutil.WriteFile(filepath.Join(c.MigrationsDir, name+"_up.sql"), []byte(up), 0600)
+	if err != nil {
+		t.Fatalf("error writing migration: %s", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(c.RollbacksDir, name+"_down.sql"), []byte(down), 0600)
+	if err != nil {
+		t.Fatalf("error writing rollback: %s", err)
+	}
+}
+
+func TestIntegrationMigrationsAreRanAndRolledBack(t *testing.T) {
+	config, cleanUp := integrationDatabase(t, "migrator_test_users", "migrator_test_posts")
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE migrator_test_users (id INT);", "DROP TABLE migrator_test_users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE migrator_test_posts (id INT);", "DROP TABLE migrator_test_posts;")
+
+	m, err := migrator.NewMigrator(config, newServicer(t, config), mock.MockLogServicer())
+	if err != nil {
+		t.Fatalf("error creating migrator: %s", err)
+	}
+
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	r, err := newServicer(t, config).RanMigrations(context.Background())
+	if err != nil || len(r) != 2 {
+		t.Fatalf("expected 2 ran migrations, got %d: %v", len(r), err)
+	}
+
+	if err := m.RollbackSteps(2); err != nil {
+		t.Fatalf("error rolling back: %s", err)
+	}
+
+	r, err = newServicer(t, config).RanMigrations(context.Background())
+	if err != nil || len(r) != 0 {
+		t.Errorf("expected no ran migrations, got %d: %v", len(r), err)
+	}
+}
+
+func TestIntegrationMigrationLockCannotBeTakenTwice(t *testing.T) {
+	config, cleanUp := integrationDatabase(t)
+	defer cleanUp()
+
+	first, second := newServicer(t, config), newServicer(t, config)
+
+	if locked, err := first.AcquireLock(context.Background(), time.Second); err != nil || !locked {
+		t.Fatalf("first lock was not acquired: %v", err)
+	}
+
+	if locked, err := second.AcquireLock(context.Background(), 0); err != nil || locked {
+		t.Errorf("second lock was acquired whilst the first was held: %v", err)
+	}
+
+	if err := first.ReleaseLock(); err != nil {
+		t.Fatalf("error releasing lock: %s", err)
+	}
+
+	if locked, err := second.AcquireLock(context.Background(), 0); err != nil || !locked {
+		t.Errorf("lock was not acquired after being released: %v", err)
+	}
+
+	second.ReleaseLock()
+}
+
+func TestIntegrationLegacyHistoryTableIsUpgraded(t *testing.T) {
+	config, cleanUp := integrationDatabase(t)
+	defer cleanUp()
+
+	db := openDatabase(t, config)
+	defer db.Close()
+
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE %[1]s
+		(
+			id        INT NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			ran       TIMESTAMP NOT NULL
+		);
+		INSERT INTO %[1]s (id, file_name, ran)
+		VALUES (1, '1_create-users_up.sql', CURRENT_TIMESTAMP);`, config.HistoryTable))
+	if err != nil {
+		t.Fatalf("error creating legacy history table: %s", err)
+	}
+
+	servicer := newServicer(t, config)
+
+	// The legacy table is readable before it is upgraded, as in a dry run.
+	if r, err := servicer.RanMigrations(context.Background()); err != nil || len(r) != 1 {
+		t.Fatalf("legacy history table was not read: %v", err)
+	}
+
+	if _, err := servicer.TryCreateHistoryTable(context.Background()); err != nil {
+		t.Fatalf("error upgrading history table: %s", err)
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT version FROM " + config.HistoryTable + "_version").Scan(&version); err != nil {
+		t.Fatalf("error reading history table version: %s", err)
+	}
+
+	if version != history.Version {
+		t.Errorf("expected version %d, got %d", history.Version, version)
+	}
+
+	var dataType string
+	db.QueryRow(`
+		SELECT data_type
+		FROM information_schema.columns
+		WHERE table_name = $1
+		AND column_name = 'id'`, config.HistoryTable).Scan(&dataType)
+	if dataType != "bigint" {
+		t.Errorf("id was not widened, it is %s", dataType)
+	}
+}
@@ -0,0 +1,56 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// MigrationFunc is a migration or rollback written in Go. It is given the
+// transaction that the migration is being ran in.
+type MigrationFunc func(tx *sql.Tx) error
+
+var (
+	registryMu sync.Mutex
+	registry   []Migration
+)
+
+// Register adds a migration written in Go to those ran by every Migrator. It
+// is ordered amongst the migration files by its ID, ran in the same
+// transaction as them and recorded in the migration history as ID_name.go.
+//
+// Register is intended to be called from an init function. It panics if up or
+// down is nil or if a migration with the same ID has already been registered.
+func Register(id int64, name string, up, down MigrationFunc) {
+	if up == nil || down == nil {
+		panic("migrator: Register migration functions must not be nil")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, r := range registry {
+		if r.ID == id {
+			panic(fmt.Sprintf("migrator: Register called twice for migration %d", id))
+		}
+	}
+
+	fileName := fmt.Sprintf("%d_%s.go", id, name)
+	registry = append(registry, Migration{
+		ID:       id,
+		FileName: fileName,
+		Up:       up,
+		Rollback: Rollback{
+			FileName: fileName,
+			Down:     down,
+		},
+	})
+}
+
+// registeredMigrations returns a copy of the migrations added with Register.
+func registeredMigrations() []Migration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return append([]Migration(nil), registry...)
+}
@@ -1,21 +1,46 @@
 package migrator
 
-// DatabaseServicer represents a service that runs the migrations.
+import (
+	"context"
+	"time"
+)
+
+// DatabaseServicer represents a service that runs the migrations. Methods that
+// talk to the database accept a context so that long running statements can
+// be cancelled or bounded by a deadline.
 type DatabaseServicer interface {
+	// AcquireLock takes the migration lock, waiting up to the timeout for
+	// another process to release it. The boolean return value indicates
+	// whether or not the lock was obtained. The lock must be held
+	// independently of the transaction so it can outlive a rollback.
+	AcquireLock(ctx context.Context, timeout time.Duration) (bool, error)
+
 	// BeginTransaction creates a transaction in the implementing database
-	// servicer.
-	BeginTransaction() error
+	// servicer. Every statement issued until the transaction is committed
+	// or rolled back must run inside it, on the same connection.
+	BeginTransaction(ctx context.Context) error
 
 	// CommitTransaction ends the created transaction providing there is one
 	// and commits it to the database.
 	CommitTransaction() error
 
+	// DefaultTransactionMode is the transaction mode used when one has not
+	// been configured.
+	DefaultTransactionMode() TransactionMode
+
+	// HistoryTableExists reports whether or not the migration history table
+	// exists, without creating it.
+	HistoryTableExists(ctx context.Context) (bool, error)
+
 	// RanMigrations retrieves all previously ran migrations.
-	RanMigrations() ([]RanMigration, error)
+	RanMigrations(ctx context.Context) ([]RanMigration, error)
+
+	// ReleaseLock releases the migration lock providing it is held.
+	ReleaseLock() error
 
 	// RemoveMigrationHistory removes the specified migration from the
 	// history table.
-	RemoveMigrationHistory(m Migration) error
+	RemoveMigrationHistory(ctx context.Context, m Migration) error
 
 	// RollbackTransaction ends the created transaction providing there is one
 	// and rolls it back, ensuring there are no changes to the database made.
@@ -23,19 +48,20 @@ type DatabaseServicer interface {
 
 	// RollbackMigration rolls back the specified migration and removes it
 	// from the RanMigrations table.
-	RollbackMigration(m Migration) error
+	RollbackMigration(ctx context.Context, m Migration) error
 
 	// RunMigration runs the specified migration against the current database.
-	RunMigration(m Migration) error
+	RunMigration(ctx context.Context, m Migration) error
 
 	// TryCreateHistoryTable creates the migration history table if it does
-	// not already exist. The boolean return value indicates whether or not
+	// not already exist, or otherwise upgrades it in place to the layout of
+	// the running release. The boolean return value indicates whether or not
 	// the table had to be created.
-	TryCreateHistoryTable() (bool, error)
+	TryCreateHistoryTable(ctx context.Context) (bool, error)
 
 	// WriteMigrationHistory writes that a migration has been ran into the
-	// migration history table.
-	WriteMigrationHistory(m Migration) error
+	// migration history table, along with the details of its execution.
+	WriteMigrationHistory(ctx context.Context, m Migration, e Execution) error
 }
 
 // LogServicer abstracts common logging functions so we do not have to
@@ -0,0 +1,239 @@
+package migrator
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MigrationSource finds the migrations to be ran along with their rollbacks.
+type MigrationSource interface {
+	// Migrations returns every migration that was found, in any order.
+	// Files that are not migrations are skipped and logged to l.
+	Migrations(l Logger) ([]Migration, error)
+}
+
+// NewFSSource creates a MigrationSource that reads migration files from
+// migrationsDir and rollback files from rollbacksDir within fsys. Any fs.FS
+// can be used, such as an embed.FS, os.DirFS or fstest.MapFS. The directories
+// are cleaned, so "./migrations/up/" and "." are accepted as fs.FS paths.
+func NewFSSource(fsys fs.FS, migrationsDir, rollbacksDir string) MigrationSource {
+	return fsSource{
+		fsys:          fsys,
+		migrationsDir: path.Clean(migrationsDir),
+		rollbacksDir:  path.Clean(rollbacksDir),
+	}
+}
+
+// NewDirectorySource creates a MigrationSource that reads migration and
+// rollback files from directories on the operating system's file system.
+// This is the source used when Configuration.FS is not set.
+func NewDirectorySource(migrationsDir, rollbacksDir string) MigrationSource {
+	return NewFSSource(osFS{}, migrationsDir, rollbacksDir)
+}
+
+// osFS is an fs.FS that opens paths on the operating system's file system as
+// they are given. Unlike os.DirFS it accepts absolute and parent paths, which
+// keeps MigrationsDir and RollbacksDir working as they always have.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+type fsSource struct {
+	fsys          fs.FS
+	migrationsDir string
+	rollbacksDir  string
+}
+
+func (s fsSource) Migrations(l Logger) ([]Migration, error) {
+	migrationFiles, err := fs.ReadDir(s.fsys, s.migrationsDir)
+	if err != nil {
+		return nil, NewErrSearchingDir(s.migrationsDir, err)
+	}
+
+	if len(migrationFiles) == 0 {
+		return nil, ErrNoMigrationsInDir
+	}
+
+	var rollbackFiles []fs.DirEntry
+	migrations := make([]Migration, 0, len(migrationFiles))
+
+	for _, migration := range migrationFiles {
+		// Each migration/rollback file name should be of format:
+		// id_name_up/down.sql, or id_name.sql for a single-file migration
+		// containing both sections. If they are not, we should not include
+		// them.
+		fileName, ok := parseFileName(migration.Name())
+		if !ok || fileName.action == "down" {
+			l.Debug("skipping file, does not have an appropriate file name",
+				LogKeyPhase, phaseDiscover,
+				LogKeyFileName, migration.Name())
+			continue
+		}
+
+		file, err := s.readFile(s.migrationsDir, migration.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		up, down, hasUp, hasDown := splitSections(file)
+		if hasUp {
+			if !hasDown {
+				return nil, NewErrMissingRollbackFile(migration.Name())
+			}
+
+			migrationID, err := fileName.parseID()
+			if err != nil {
+				return nil, NewErrInvalidMigrationID(migration.Name(), err)
+			}
+
+			migrations = append(migrations, Migration{
+				ID:            migrationID,
+				FileName:      migration.Name(),
+				FileContents:  up,
+				NoTransaction: hasNoTransactionDirective(up),
+				Rollback: Rollback{
+					FileName:      migration.Name(),
+					FileContents:  down,
+					NoTransaction: hasNoTransactionDirective(down),
+				},
+			})
+			continue
+		}
+
+		if fileName.action != "up" {
+			l.Debug("skipping file, does not have an appropriate file name",
+				LogKeyPhase, phaseDiscover,
+				LogKeyFileName, migration.Name())
+			continue
+		}
+
+		// Only migrations split across the two directories need the
+		// rollbacks directory, so it is not read until one is found.
+		if rollbackFiles == nil {
+			rollbackFiles, err = fs.ReadDir(s.fsys, s.rollbacksDir)
+			if err != nil {
+				return nil, NewErrSearchingDir(s.rollbacksDir, err)
+			}
+
+			if len(rollbackFiles) == 0 {
+				return nil, ErrNoRollbacksInDir
+			}
+		}
+
+		rollback, err := s.findRollback(l, fileName.key(), rollbackFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		migrationID, err := fileName.parseID()
+		if err != nil {
+			return nil, NewErrInvalidMigrationID(migration.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{
+			ID:            migrationID,
+			FileName:      migration.Name(),
+			FileContents:  file,
+			Rollback:      rollback,
+			NoTransaction: hasNoTransactionDirective(file),
+		})
+	}
+
+	return migrations, nil
+}
+
+func (s fsSource) readFile(dir, name string) ([]byte, error) {
+	file, err := fs.ReadFile(s.fsys, path.Join(dir, name))
+	if err != nil {
+		return nil, NewErrReadingFile(name, err)
+	}
+
+	return file, nil
+}
+
+func (s fsSource) findRollback(l Logger, migName string, rbs []fs.DirEntry) (Rollback, error) {
+	for _, r := range rbs {
+		rollbackName, ok := parseFileName(r.Name())
+		if !ok || rollbackName.action != "down" {
+			l.Debug("skipping file, does not have an appropriate file name",
+				LogKeyPhase, phaseDiscover,
+				LogKeyFileName, r.Name())
+			continue
+		}
+
+		if !strings.EqualFold(rollbackName.key(), migName) {
+			continue
+		}
+
+		file, err := s.readFile(s.rollbacksDir, r.Name())
+		if err != nil {
+			return Rollback{}, err
+		}
+
+		return Rollback{
+			FileName:      r.Name(),
+			FileContents:  file,
+			NoTransaction: hasNoTransactionDirective(file),
+		}, nil
+	}
+
+	return Rollback{}, NewErrMissingRollbackFile(migName)
+}
+
+// migrationFileName is a migration or rollback file name split into its parts.
+type migrationFileName struct {
+	// id is the ID as it was written, which may be zero-padded.
+	id string
+
+	// name is the name of the migration, which may contain underscores.
+	name string
+
+	// action is up or down, or empty for a single-file migration.
+	action string
+}
+
+// parseFileName splits a file name of the format id_name_action.sql or
+// id_name.sql. The ID is read from before the first underscore and the action
+// from after the last, so the name in between may contain underscores.
+func parseFileName(fileName string) (migrationFileName, bool) {
+	base := strings.TrimSuffix(fileName, path.Ext(fileName))
+
+	parts := strings.Split(base, "_")
+	if len(parts) < 2 {
+		return migrationFileName{}, false
+	}
+
+	parsed := migrationFileName{
+		id:   parts[0],
+		name: strings.Join(parts[1:], "_"),
+	}
+
+	action := strings.ToLower(parts[len(parts)-1])
+	if len(parts) > 2 && (action == "up" || action == "down") {
+		parsed.name = strings.Join(parts[1:len(parts)-1], "_")
+		parsed.action = action
+	}
+
+	return parsed, true
+}
+
+// key identifies the migration a file belongs to, matching up and down files.
+func (f migrationFileName) key() string {
+	return f.id + "_" + f.name
+}
+
+// parseID converts the ID to an integer. Sequential, zero-padded and
+// YYYYMMDDHHMMSS timestamp IDs are all supported.
+func (f migrationFileName) parseID() (int64, error) {
+	return strconv.ParseInt(f.id, 10, 64)
+}
@@ -0,0 +1,144 @@
+package migrator_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+func TestFSSourceReadsMigrationsAndRollbacks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/up/1_create-users_up.sql":     {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/down/1_create-users_down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/up/2_add-posts.sql":           {Data: []byte("-- +migrate Up\nCREATE TABLE posts (id INT);\n-- +migrate Down\nDROP TABLE posts;\n")},
+	}
+
+	source := migrator.NewFSSource(fsys, "migrations/up", "migrations/down")
+	ms, err := source.Migrations(mock.MockLogger())
+	if err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(ms))
+	}
+
+	if string(ms[0].FileContents) != "CREATE TABLE users (id INT);" ||
+		string(ms[0].Rollback.FileContents) != "DROP TABLE users;" {
+		t.Errorf("migration was not read correctly: %+v", ms[0])
+	}
+
+	if ms[1].ID != 2 || string(ms[1].Rollback.FileContents) != "DROP TABLE posts;\n" {
+		t.Errorf("single-file migration was not read correctly: %+v", ms[1])
+	}
+}
+
+func TestFSSourceAcceptsUncleanDirectories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_create-users_up.sql":               {Data: []byte("CREATE TABLE users (id INT);")},
+		"down/1_create-users_down.sql":        {Data: []byte("DROP TABLE users;")},
+		"migrations/up/2_create-posts_up.sql": {Data: []byte("CREATE TABLE posts (id INT);")},
+		"migrations/2_create-posts_down.sql":  {Data: []byte("DROP TABLE posts;")},
+	}
+
+	dirs := []struct{ migrations, rollbacks, file string }{
+		{".", "./down", "1_create-users_up.sql"},
+		{"./migrations/up", "migrations/", "2_create-posts_up.sql"},
+		{"migrations/up/", "./migrations/../migrations", "2_create-posts_up.sql"},
+	}
+
+	for _, d := range dirs {
+		ms, err := migrator.NewFSSource(fsys, d.migrations, d.rollbacks).Migrations(mock.MockLogger())
+		if err != nil {
+			t.Errorf("error reading from %q and %q: %s", d.migrations, d.rollbacks, err)
+			continue
+		}
+
+		if len(ms) != 1 || ms[0].FileName != d.file || len(ms[0].Rollback.FileContents) == 0 {
+			t.Errorf("migrations were not read from %q and %q: %+v", d.migrations, d.rollbacks, ms)
+		}
+	}
+}
+
+func TestFSSourceWithAMissingDirectoryResultsInAnError(t *testing.T) {
+	source := migrator.NewFSSource(fstest.MapFS{}, "migrations/up", "migrations/down")
+
+	if _, err := source.Migrations(mock.MockLogger()); err == nil {
+		t.Errorf("error was not returned when it should have been")
+	}
+}
+
+func TestConfiguredFSIsUsedToFindMigrations(t *testing.T) {
+	config := mock.ValidConfiguration()
+	config.FS = fstest.MapFS{
+		"my-migrations-directory/1_create-users_up.sql":  {Data: []byte("CREATE TABLE users (id INT);")},
+		"my-rollbacks-directory/1_create-users_down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	var ran []string
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RunMigrationFunc = func(m migrator.Migration) error {
+		ran = append(ran, m.FileName)
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(ran) != 1 || ran[0] != "1_create-users_up.sql" {
+		t.Errorf("migrations were not read from the configured file system: %v", ran)
+	}
+}
+
+func TestTimestampAndZeroPaddedIDsAndUnderscoredNamesAreParsed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"up/20260101120000_add_users_table_up.sql":     {Data: []byte("CREATE TABLE users (id INT);")},
+		"down/20260101120000_add_users_table_down.sql": {Data: []byte("DROP TABLE users;")},
+		"up/0002_add_posts_up.sql":                     {Data: []byte("CREATE TABLE posts (id INT);")},
+		"down/0002_add_posts_down.sql":                 {Data: []byte("DROP TABLE posts;")},
+	}
+
+	ms, err := migrator.NewFSSource(fsys, "up", "down").Migrations(mock.MockLogger())
+	if err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(ms))
+	}
+
+	if ms[0].ID != 2 || ms[0].Rollback.FileName != "0002_add_posts_down.sql" {
+		t.Errorf("zero-padded migration was not parsed correctly: %+v", ms[0])
+	}
+
+	if ms[1].ID != 20260101120000 || ms[1].Rollback.FileName != "20260101120000_add_users_table_down.sql" {
+		t.Errorf("timestamp migration was not parsed correctly: %+v", ms[1])
+	}
+}
+
+func TestDuplicateMigrationIDsResultInAnError(t *testing.T) {
+	config := mock.ValidConfiguration()
+	config.FS = fstest.MapFS{
+		"my-migrations-directory/4_create-users_up.sql":   {},
+		"my-rollbacks-directory/4_create-users_down.sql":  {},
+		"my-migrations-directory/04_create-posts_up.sql":  {},
+		"my-rollbacks-directory/04_create-posts_down.sql": {},
+	}
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), mock.MockLogServicer())
+	err := m.Migrate()
+
+	dup, ok := err.(migrator.ErrDuplicateMigrationID)
+	if !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if dup.ID != 4 || len(dup.Files) != 2 {
+		t.Errorf("duplicate migrations were not reported: %+v", dup)
+	}
+}
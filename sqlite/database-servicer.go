@@ -0,0 +1,291 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/internal/sqlutil"
+
+	// Import the required SQLite driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrNoTransaction is an error that is raised when a transaction is committed
+// or rolled back without one having been started.
+var ErrNoTransaction = errors.New("no transaction has been started")
+
+// ErrHistorySchemaNotSupported is an error that is raised when the history
+// table is configured to live in a schema other than main.
+var ErrHistorySchemaNotSupported = errors.New("sqlite history table must be in the main database")
+
+// lockPollInterval is how often the lock table is retried whilst another
+// process holds the migration lock.
+const lockPollInterval = 100 * time.Millisecond
+
+// DefaultStaleLockAge is how long the migration lock may go without being
+// refreshed before another process takes it over, unless changed with
+// WithStaleLockAge.
+const DefaultStaleLockAge = time.Hour
+
+// Option configures the SQLite database servicer.
+type Option func(s *sqlite)
+
+// WithStaleLockAge sets how long the migration lock may go without being
+// refreshed before another process takes it over. The lock is a row in a
+// table rather than a session lock, so a process that crashes without
+// releasing it would otherwise block every later run. The lock is refreshed
+// before each migration and rollback, so the age must be longer than the
+// slowest of them.
+func WithStaleLockAge(age time.Duration) Option {
+	return func(s *sqlite) {
+		s.staleLockAge = age
+	}
+}
+
+// NewSQLiteDatabaseServicer creates an implementation of the DatabaseServicer
+// for the SQLite database engine. The connection string is either the path to
+// a database file or ":memory:" for an in-memory database.
+func NewSQLiteDatabaseServicer(cs string, opts ...Option) (migrator.DatabaseServicer, error) {
+	db, err := sql.Open("sqlite3", cs)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only allows a single writer and every new connection to an
+	// in-memory database gets a database of its own, so the pool is limited
+	// to one connection.
+	db.SetMaxOpenConns(1)
+
+	s := &sqlite{
+		db:           db,
+		table:        migrator.DefaultHistoryTable,
+		staleLockAge: DefaultStaleLockAge,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// sqlite has no advisory locks, so the migration lock is a row in a lock
+// table which only one process can insert at a time.
+type sqlite struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	locked bool
+
+	// staleLockAge is how old the lock must be for it to be taken over.
+	staleLockAge time.Duration
+
+	// schema and table locate the migration history table; the schema is
+	// either empty or main.
+	schema string
+	table  string
+}
+
+func (s *sqlite) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if s.tx != nil {
+		return s.tx.ExecContext(ctx, query, args...)
+	}
+
+	return s.db.ExecContext(ctx, query, args...)
+}
+
+func (s *sqlite) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if s.tx != nil {
+		return s.tx.QueryContext(ctx, query, args...)
+	}
+
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+func (s *sqlite) AcquireLock(ctx context.Context, timeout time.Duration) (bool, error) {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+s.lockTable()+`
+		(
+			id     INTEGER NOT NULL PRIMARY KEY,
+			locked DATETIME NOT NULL
+		)`)
+	if err != nil {
+		return false, err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		// Take over a lock left behind by a process that did not release it.
+		_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s
+			WHERE id = 1
+			AND julianday(locked) < julianday('now', ?)
+		`, s.lockTable()), fmt.Sprintf("-%f seconds", s.staleLockAge.Seconds()))
+		if err != nil {
+			return false, err
+		}
+
+		res, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+			INSERT OR IGNORE INTO %s (id, locked)
+			VALUES (1, ?)
+		`, s.lockTable()), time.Now())
+		if err != nil {
+			return false, err
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return false, err
+		} else if n == 1 {
+			s.locked = true
+			return true, nil
+		}
+
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// refreshLock records that the lock is still held so that it is not taken
+// over as stale during a long run. Within a transaction the refresh is
+// committed along with the step; until then, SQLite's single writer keeps
+// other processes from taking the lock over.
+func (s *sqlite) refreshLock(ctx context.Context) error {
+	if !s.locked {
+		return nil
+	}
+
+	_, err := s.exec(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET locked = ?
+		WHERE id = 1
+	`, s.lockTable()), time.Now())
+
+	return err
+}
+
+func (s *sqlite) ReleaseLock() error {
+	if !s.locked {
+		return nil
+	}
+
+	s.locked = false
+
+	_, err := s.db.Exec("DELETE FROM " + s.lockTable() + " WHERE id = 1")
+
+	return err
+}
+
+func (s *sqlite) RunMigration(ctx context.Context, mi migrator.Migration) error {
+	if err := s.refreshLock(ctx); err != nil {
+		return err
+	}
+
+	if mi.Up != nil {
+		return sqlutil.RunFunc(ctx, s.db, s.tx, mi.Up)
+	}
+
+	_, err := s.exec(ctx, string(mi.FileContents))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sqlite) BeginTransaction(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	s.tx = tx
+
+	return nil
+}
+
+func (s *sqlite) RanMigrations(ctx context.Context) ([]migrator.RanMigration, error) {
+	return s.history().RanMigrations(ctx, s.query)
+}
+
+func (s *sqlite) RemoveMigrationHistory(ctx context.Context, mi migrator.Migration) error {
+	_, err := s.exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE id = ?`, s.historyTable()), mi.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sqlite) RollbackMigration(ctx context.Context, mi migrator.Migration) error {
+	if err := s.refreshLock(ctx); err != nil {
+		return err
+	}
+
+	if mi.Rollback.Down != nil {
+		return sqlutil.RunFunc(ctx, s.db, s.tx, mi.Rollback.Down)
+	}
+
+	_, err := s.exec(ctx, string(mi.Rollback.FileContents))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DefaultTransactionMode is a single transaction for the whole run as SQLite
+// supports transactional DDL.
+func (s *sqlite) DefaultTransactionMode() migrator.TransactionMode {
+	return migrator.TransactionModeAll
+}
+
+func (s *sqlite) HistoryTableExists(ctx context.Context) (bool, error) {
+	return s.tableExists(ctx, s.table)
+}
+
+func (s *sqlite) CommitTransaction() error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+
+	err := s.tx.Commit()
+	s.tx = nil
+
+	return err
+}
+
+func (s *sqlite) RollbackTransaction() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	err := s.tx.Rollback()
+	s.tx = nil
+
+	return err
+}
+
+func (s *sqlite) WriteMigrationHistory(ctx context.Context, mi migrator.Migration, e migrator.Execution) error {
+	_, err := s.exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, file_name, ran, checksum, duration_ms, host, username, migrator_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.historyTable()), mi.ID, mi.FileName, time.Now(), mi.Checksum(), e.Duration.Milliseconds(), e.Host, e.User, e.Version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,724 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/internal/history"
+	"github.com/bunsenapp/migrator/mock"
+	"github.com/bunsenapp/migrator/sqlite"
+)
+
+// testDatabase creates a SQLite database file along with migration and
+// rollback directories, returning a configuration pointing at them and a
+// clean up function.
+func testDatabase(t *testing.T) (migrator.Configuration, func()) {
+	dir, err := ioutil.TempDir("", "migrator-sqlite")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: %s", err)
+	}
+
+	config := migrator.Configuration{
+		DatabaseConnectionString: filepath.Join(dir, "test.db"),
+		MigrationsDir:            filepath.Join(dir, "up"),
+		RollbacksDir:             filepath.Join(dir, "down"),
+	}
+
+	os.Mkdir(config.MigrationsDir, 0700)
+	os.Mkdir(config.RollbacksDir, 0700)
+
+	return config, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func writeMigration(t *testing.T, c migrator.Configuration, name, up, down string) {
+	err := ioutil.WriteFile(filepath.Join(c.MigrationsDir, fmt.Sprintf("%s_up.sql", name)), []byte(up), 0600)
+	if err != nil {
+		t.Fatalf("error writing migration: %s", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(c.RollbacksDir, fmt.Sprintf("%s_down.sql", name)), []byte(down), 0600)
+	if err != nil {
+		t.Fatalf("error writing rollback: %s", err)
+	}
+}
+
+func newMigrator(t *testing.T, c migrator.Configuration) migrator.Migrator {
+	db, err := sqlite.NewSQLiteDatabaseServicer(c.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	m, err := migrator.NewMigrator(c, db, mock.MockLogServicer())
+	if err != nil {
+		t.Fatalf("error creating migrator: %s", err)
+	}
+
+	return m
+}
+
+func tableExists(t *testing.T, c migrator.Configuration, table string) bool {
+	db, err := sql.Open("sqlite3", c.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	var count int
+	err = db.QueryRow(`
+		SELECT COUNT(*)
+		FROM sqlite_master
+		WHERE type = 'table'
+		AND name = ?`, table).Scan(&count)
+	if err != nil {
+		t.Fatalf("error querying database: %s", err)
+	}
+
+	return count > 0
+}
+
+func ranMigrations(t *testing.T, c migrator.Configuration) []migrator.RanMigration {
+	db, err := sqlite.NewSQLiteDatabaseServicer(c.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	r, err := db.RanMigrations(context.Background())
+	if err != nil {
+		t.Fatalf("error retrieving ran migrations: %s", err)
+	}
+
+	return r
+}
+
+func TestMigrationsAreRanAndWrittenToTheHistoryTable(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE posts (id INTEGER);", "DROP TABLE posts;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	if !tableExists(t, config, "users") || !tableExists(t, config, "posts") {
+		t.Errorf("migrations were not ran when they should have been")
+	}
+
+	if r := ranMigrations(t, config); len(r) != 2 {
+		t.Errorf("expected 2 ran migrations, got %d", len(r))
+	}
+}
+
+func TestIfMigrationHasAlreadyBeenDeployedItIsNotRanInAgain(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	// Running the migration again would fail as the table already exists.
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Errorf("migration was ran in again: %s", err)
+	}
+
+	if r := ranMigrations(t, config); len(r) != 1 {
+		t.Errorf("expected 1 ran migration, got %d", len(r))
+	}
+}
+
+func TestErrorDuringMigrationRunResultsInTransactionBeingRolledBack(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_broken", "CREATE TABLE;", "")
+
+	err := newMigrator(t, config).Migrate()
+	if _, ok := err.(migrator.ErrRunningMigration); !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if tableExists(t, config, "users") {
+		t.Errorf("transaction was not rolled back after an error occurred")
+	}
+
+	if r := ranMigrations(t, config); len(r) != 0 {
+		t.Errorf("expected 0 ran migrations, got %d", len(r))
+	}
+}
+
+func TestYouCanRollbackTheLatestMigration(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE posts (id INTEGER);", "DROP TABLE posts;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	if err := newMigrator(t, config).Rollback("2_create-posts_up.sql"); err != nil {
+		t.Fatalf("error rolling back: %s", err)
+	}
+
+	if tableExists(t, config, "posts") {
+		t.Errorf("migration was not rolled back successfully")
+	}
+
+	r := ranMigrations(t, config)
+	if len(r) != 1 || r[0].ID != 1 {
+		t.Errorf("migration history was not removed")
+	}
+}
+
+func TestYouCannotRollbackANotLatestMigration(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE posts (id INTEGER);", "DROP TABLE posts;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	err := newMigrator(t, config).Rollback("1_create-users_up.sql")
+	if err != migrator.ErrNotLatestMigration {
+		t.Errorf("error was not returned when it should have been")
+	}
+
+	if !tableExists(t, config, "users") {
+		t.Errorf("migration was rolled back when it should not have been")
+	}
+}
+
+func TestMigrationLockCannotBeTakenTwice(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	first, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	second, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if locked, err := first.AcquireLock(context.Background(), time.Second); err != nil || !locked {
+		t.Fatalf("first lock was not acquired: %v", err)
+	}
+
+	if locked, err := second.AcquireLock(context.Background(), 0); err != nil || locked {
+		t.Errorf("second lock was acquired whilst the first was held: %v", err)
+	}
+
+	if err := first.ReleaseLock(); err != nil {
+		t.Fatalf("error releasing lock: %s", err)
+	}
+
+	if locked, err := second.AcquireLock(context.Background(), 0); err != nil || !locked {
+		t.Errorf("lock was not acquired after being released: %v", err)
+	}
+}
+
+func TestStaleMigrationLockIsTakenOver(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	db, err := sql.Open("sqlite3", config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	// A lock left behind by a process that crashed long ago.
+	_, err = db.Exec(`
+		CREATE TABLE migration_lock
+		(
+			id     INTEGER NOT NULL PRIMARY KEY,
+			locked DATETIME NOT NULL
+		);
+		INSERT INTO migration_lock (id, locked)
+		VALUES (1, '2020-01-01 00:00:00');`)
+	if err != nil {
+		t.Fatalf("error creating stale lock: %s", err)
+	}
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+
+	config.LockTimeout = 200 * time.Millisecond
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	if !tableExists(t, config, "users") {
+		t.Errorf("migration was not ran after taking over the stale lock")
+	}
+
+	servicer, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if locked, err := servicer.AcquireLock(context.Background(), 0); err != nil || !locked {
+		t.Errorf("lock was not released after the run: %v", err)
+	}
+
+	// A recently taken lock must not be treated as stale.
+	other, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if locked, err := other.AcquireLock(context.Background(), 0); err != nil || locked {
+		t.Errorf("lock that is held was taken over as stale: %v", err)
+	}
+}
+
+func TestStaleLockAgeCanBeConfigured(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	first, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	second, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString, sqlite.WithStaleLockAge(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if locked, err := first.AcquireLock(context.Background(), 0); err != nil || !locked {
+		t.Fatalf("first lock was not acquired: %v", err)
+	}
+
+	if locked, err := second.AcquireLock(context.Background(), time.Second); err != nil || !locked {
+		t.Errorf("lock older than the configured age was not taken over: %v", err)
+	}
+}
+
+func TestMigrationLockIsRefreshedBeforeEachMigration(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	first, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if locked, err := first.AcquireLock(context.Background(), 0); err != nil || !locked {
+		t.Fatalf("lock was not acquired: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	// Age the lock as a long run would, then run a migration outside of a
+	// transaction.
+	db.Exec("UPDATE migration_lock SET locked = '2020-01-01 00:00:00'")
+
+	err = first.RunMigration(context.Background(), migrator.Migration{FileContents: []byte("CREATE TABLE users (id INTEGER);")})
+	if err != nil {
+		t.Fatalf("error running migration: %s", err)
+	}
+
+	second, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if locked, err := second.AcquireLock(context.Background(), 0); err != nil || locked {
+		t.Errorf("lock was taken over whilst a run was still migrating: %v", err)
+	}
+}
+
+func TestEditingAnAppliedMigrationIsDetected(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER, name TEXT);", "DROP TABLE users;")
+
+	err := newMigrator(t, config).Migrate()
+	if _, ok := err.(migrator.ErrMigrationDrift); !ok {
+		t.Errorf("error returned was not correct: %v", err)
+	}
+}
+
+func TestHistoryTableWithoutChecksumColumnIsUpgraded(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	db, err := sql.Open("sqlite3", config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE migration_history
+		(
+			id        INTEGER NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			ran       DATETIME NOT NULL
+		);
+		INSERT INTO migration_history (id, file_name, ran)
+		VALUES (1, '1_create-users_up.sql', CURRENT_TIMESTAMP);
+		CREATE TABLE users (id INTEGER);`)
+	if err != nil {
+		t.Fatalf("error creating legacy history table: %s", err)
+	}
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE posts (id INTEGER);", "DROP TABLE posts;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	r := ranMigrations(t, config)
+	if len(r) != 2 {
+		t.Fatalf("expected 2 ran migrations, got %d", len(r))
+	}
+
+	for _, rm := range r {
+		if rm.ID == 2 && rm.Checksum == "" {
+			t.Errorf("checksum was not recorded after upgrading the history table")
+		}
+
+		if rm.ID == 2 && rm.Version == "" {
+			t.Errorf("execution was not recorded after upgrading the history table")
+		}
+
+		if rm.ID == 1 && rm.Version != "" {
+			t.Errorf("legacy migration unexpectedly has a version: %s", rm.Version)
+		}
+	}
+}
+
+func TestLegacyHistoryTableIsUpgradedToTheLatestVersion(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	db, err := sql.Open("sqlite3", config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE migration_history
+		(
+			id        INTEGER NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			ran       DATETIME NOT NULL
+		);
+		INSERT INTO migration_history (id, file_name, ran)
+		VALUES (1, '1_create-users_up.sql', CURRENT_TIMESTAMP);`)
+	if err != nil {
+		t.Fatalf("error creating legacy history table: %s", err)
+	}
+
+	servicer, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	// Upgrading a second time, and again once the recorded version is lost,
+	// must leave the table as it is.
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			db.Exec("DROP TABLE migration_history_version")
+		}
+
+		if _, err := servicer.TryCreateHistoryTable(context.Background()); err != nil {
+			t.Fatalf("error upgrading history table on attempt %d: %s", i+1, err)
+		}
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT version FROM migration_history_version").Scan(&version); err != nil {
+		t.Fatalf("error reading history table version: %s", err)
+	}
+
+	if version != history.Version {
+		t.Errorf("expected version %d, got %d", history.Version, version)
+	}
+
+	var pk int
+	db.QueryRow("SELECT pk FROM pragma_table_info('migration_history') WHERE name = 'id'").Scan(&pk)
+	if pk == 0 {
+		t.Errorf("id is not the primary key of the upgraded table")
+	}
+
+	if !tableExists(t, config, "migration_history") {
+		t.Fatalf("history table was lost during the upgrade")
+	}
+
+	var indexes int
+	db.QueryRow("SELECT COUNT(*) FROM pragma_index_list('migration_history') WHERE name = 'migration_history_file_name_idx'").Scan(&indexes)
+	if indexes != 1 {
+		t.Errorf("file_name index was not created")
+	}
+
+	r := ranMigrations(t, config)
+	if len(r) != 1 || r[0].FileName != "1_create-users_up.sql" {
+		t.Errorf("ran migrations were not kept during the upgrade: %+v", r)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO migration_history (id, file_name, ran)
+		VALUES (1, '1_create-users_up.sql', CURRENT_TIMESTAMP)`)
+	if err == nil {
+		t.Errorf("a migration could be recorded twice")
+	}
+}
+
+func TestDryRunAgainstALegacyHistoryTableDoesNotUpgradeIt(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	db, err := sql.Open("sqlite3", config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE users (id INTEGER);
+		CREATE TABLE migration_history
+		(
+			id        INTEGER NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			ran       DATETIME NOT NULL
+		);
+		INSERT INTO migration_history (id, file_name, ran)
+		VALUES (1, '1_create-users_up.sql', CURRENT_TIMESTAMP);`)
+	if err != nil {
+		t.Fatalf("error creating legacy history table: %s", err)
+	}
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE posts (id INTEGER);", "DROP TABLE posts;")
+
+	config.DryRun = true
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error during dry run: %s", err)
+	}
+
+	if tableExists(t, config, "posts") {
+		t.Errorf("migration was ran during a dry run")
+	}
+
+	if tableExists(t, config, "migration_history_version") {
+		t.Errorf("history table was upgraded during a dry run")
+	}
+
+	r := ranMigrations(t, config)
+	if len(r) != 1 || r[0].FileName != "1_create-users_up.sql" || r[0].Checksum != "" {
+		t.Errorf("unexpected ran migrations read from the legacy table: %+v", r)
+	}
+}
+
+func TestHistoryTableFromANewerReleaseResultsInAnError(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	servicer, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if _, err := servicer.TryCreateHistoryTable(context.Background()); err != nil {
+		t.Fatalf("error creating history table: %s", err)
+	}
+
+	db, err := sql.Open("sqlite3", config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	db.Exec("UPDATE migration_history_version SET version = ?", history.Version+1)
+
+	_, err = servicer.TryCreateHistoryTable(context.Background())
+	if _, ok := err.(migrator.ErrUnsupportedHistoryVersion); !ok {
+		t.Errorf("expected ErrUnsupportedHistoryVersion, got %v", err)
+	}
+}
+
+func TestExecutionDetailsAreRecordedInTheHistoryTable(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	migrator.Version = "v9.9.9"
+	defer func() { migrator.Version = "" }()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	r := ranMigrations(t, config)
+	if len(r) != 1 {
+		t.Fatalf("expected 1 ran migration, got %d", len(r))
+	}
+
+	host, _ := os.Hostname()
+	if r[0].Host != host {
+		t.Errorf("expected host %q, got %q", host, r[0].Host)
+	}
+
+	if r[0].User == "" {
+		t.Errorf("user was not recorded")
+	}
+
+	if r[0].Version != "v9.9.9" {
+		t.Errorf("expected version v9.9.9, got %q", r[0].Version)
+	}
+
+	if r[0].Duration < 0 {
+		t.Errorf("unexpected negative duration: %s", r[0].Duration)
+	}
+}
+
+func TestApplicationsSharingADatabaseUseTheirOwnHistoryTables(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+
+	billing := config
+	billing.HistorySchema = "main"
+	billing.HistoryTable = "billing_history"
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	writeMigration(t, billing, "1_create-invoices", "CREATE TABLE invoices (id INTEGER);", "DROP TABLE invoices;")
+	os.Remove(filepath.Join(config.MigrationsDir, "1_create-users_up.sql"))
+	os.Remove(filepath.Join(config.RollbacksDir, "1_create-users_down.sql"))
+
+	if err := newMigrator(t, billing).Migrate(); err != nil {
+		t.Fatalf("error migrating the second application: %s", err)
+	}
+
+	for _, table := range []string{"migration_history", "billing_history", "billing_lock", "invoices"} {
+		if !tableExists(t, config, table) {
+			t.Errorf("expected table %s to exist", table)
+		}
+	}
+
+	statuses, err := newMigrator(t, billing).Status()
+	if err != nil {
+		t.Fatalf("error retrieving status: %s", err)
+	}
+
+	if len(statuses) != 1 || statuses[0].FileName != "1_create-invoices_up.sql" {
+		t.Errorf("expected only the second application's migration, got %+v", statuses)
+	}
+}
+
+func TestHistorySchemaOtherThanMainResultsInAnError(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+
+	config.HistorySchema = "ops"
+
+	if err := newMigrator(t, config).Migrate(); err != sqlite.ErrHistorySchemaNotSupported {
+		t.Errorf("expected ErrHistorySchemaNotSupported, got %v", err)
+	}
+
+	if tableExists(t, config, "users") {
+		t.Errorf("migration was ran despite the history schema being rejected")
+	}
+}
+
+func TestRollbackToRollsBackEveryLaterMigration(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	writeMigration(t, config, "1_create-users", "CREATE TABLE users (id INTEGER);", "DROP TABLE users;")
+	writeMigration(t, config, "2_create-posts", "CREATE TABLE posts (id INTEGER);", "DROP TABLE posts;")
+	writeMigration(t, config, "3_create-tags", "CREATE TABLE tags (id INTEGER);", "DROP TABLE tags;")
+
+	if err := newMigrator(t, config).Migrate(); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	if err := newMigrator(t, config).RollbackTo(1); err != nil {
+		t.Fatalf("error rolling back: %s", err)
+	}
+
+	if !tableExists(t, config, "users") || tableExists(t, config, "posts") || tableExists(t, config, "tags") {
+		t.Errorf("migrations were not rolled back to the target")
+	}
+
+	r := ranMigrations(t, config)
+	if len(r) != 1 || r[0].ID != 1 {
+		t.Errorf("migration history was not removed")
+	}
+}
+
+func TestGoMigrationIsRanInTheOpenTransaction(t *testing.T) {
+	config, cleanUp := testDatabase(t)
+	defer cleanUp()
+
+	db, err := sqlite.NewSQLiteDatabaseServicer(config.DatabaseConnectionString)
+	if err != nil {
+		t.Fatalf("error creating database servicer: %s", err)
+	}
+
+	if err := db.BeginTransaction(context.Background()); err != nil {
+		t.Fatalf("error beginning transaction: %s", err)
+	}
+
+	err = db.RunMigration(context.Background(), migrator.Migration{
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE users (id INTEGER);")
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatalf("error running Go migration: %s", err)
+	}
+
+	if err := db.RollbackTransaction(); err != nil {
+		t.Fatalf("error rolling back transaction: %s", err)
+	}
+
+	if tableExists(t, config, "users") {
+		t.Errorf("Go migration was not ran in the open transaction")
+	}
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bunsenapp/migrator/internal/history"
+)
+
+// SetHistoryTable sets the schema and name of the migration history table.
+// Databases attached with ATTACH belong to a single connection of the pool,
+// so the only schema accepted is main.
+func (s *sqlite) SetHistoryTable(schema, table string) error {
+	if schema != "" && schema != "main" {
+		return ErrHistorySchemaNotSupported
+	}
+
+	s.schema = schema
+	s.table = table
+
+	return nil
+}
+
+// historyTable returns the quoted, schema qualified name of the history
+// table for use in statements.
+func (s *sqlite) historyTable() string {
+	return s.qualify(s.table)
+}
+
+// lockTable returns the quoted, schema qualified name of the table holding
+// the migration lock. It is named after the history table, less any
+// "_history" suffix, so the default history table keeps its migration_lock.
+func (s *sqlite) lockTable() string {
+	return s.qualify(strings.TrimSuffix(s.table, "_history") + "_lock")
+}
+
+// qualify quotes a table name and prefixes it with the schema, if there is
+// one.
+func (s *sqlite) qualify(table string) string {
+	return history.QualifiedName(`"`, s.schema, table)
+}
+
+// schemaName returns the name of the database holding the history table.
+func (s *sqlite) schemaName() string {
+	if s.schema == "" {
+		return "main"
+	}
+
+	return s.schema
+}
+
+// history describes the history table for the shared creation and upgrade
+// handling. SQLite integers are always 64 bit and DATETIME values are stored
+// as they are given, so there is nothing to do for versions 2 and 5.
+func (s *sqlite) history() history.Table {
+	return history.Table{
+		DB:           s.db,
+		Name:         s.table,
+		Qualify:      s.qualify,
+		TableExists:  s.tableExists,
+		ColumnExists: s.columnExists,
+		IntegerType:  "INTEGER",
+		Create: func(ctx context.Context) error {
+			if err := s.createHistoryTable(ctx, s.db.ExecContext, s.historyTable()); err != nil {
+				return err
+			}
+
+			return s.addFileNameIndex(ctx)
+		},
+		Upgrades: map[int]history.Upgrade{
+			3: s.addPrimaryKey,
+			4: s.addFileNameIndex,
+		},
+	}
+}
+
+func (s *sqlite) TryCreateHistoryTable(ctx context.Context) (bool, error) {
+	return s.history().TryCreate(ctx)
+}
+
+// createHistoryTable creates a table with the layout of the history table at
+// history.Version.
+func (s *sqlite) createHistoryTable(ctx context.Context, exec execFunc, table string) error {
+	_, err := exec(ctx, `
+		CREATE TABLE `+table+`
+		(
+			id               INTEGER NOT NULL PRIMARY KEY,
+			file_name        VARCHAR(255) NOT NULL,
+			ran              DATETIME NOT NULL,
+			checksum         VARCHAR(64) NULL,
+			duration_ms      INTEGER NULL,
+			host             VARCHAR(255) NULL,
+			username         VARCHAR(255) NULL,
+			migrator_version VARCHAR(64) NULL
+		)`)
+
+	return err
+}
+
+// addPrimaryKey makes id the primary key so that a migration cannot be
+// recorded twice. SQLite cannot add a primary key to an existing table, so
+// the table is rebuilt within a transaction. It fails if the table already
+// holds duplicate IDs.
+func (s *sqlite) addPrimaryKey(ctx context.Context) error {
+	var pk int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT pk
+		FROM pragma_table_info(?, ?)
+		WHERE name = 'id'
+	`, s.table, s.schemaName()).Scan(&pk)
+	if err != nil || pk > 0 {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rebuilt := s.table + "_upgrade"
+
+	if err = s.createHistoryTable(ctx, tx.ExecContext, s.qualify(rebuilt)); err != nil {
+		return err
+	}
+
+	columns := history.Columns()
+
+	statements := []string{
+		fmt.Sprintf("INSERT INTO %[1]s (%[3]s) SELECT %[3]s FROM %[2]s",
+			s.qualify(rebuilt), s.historyTable(), strings.Join(columns, ", ")),
+		"DROP TABLE " + s.historyTable(),
+		"ALTER TABLE " + s.qualify(rebuilt) + " RENAME TO " + history.QualifiedName(`"`, "", s.table),
+	}
+
+	for _, statement := range statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addFileNameIndex indexes file_name, which rollbacks and the status command
+// look migrations up by.
+func (s *sqlite) addFileNameIndex(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s
+		ON %s (file_name)`, s.qualify(s.table+"_file_name_idx"), history.QualifiedName(`"`, "", s.table)))
+
+	return err
+}
+
+// tableExists reports whether a table exists in the history table's schema.
+func (s *sqlite) tableExists(ctx context.Context, table string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE type = 'table'
+		AND name = ?
+	`, s.qualify("sqlite_master")), table).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// columnExists reports whether the history table has a column.
+func (s *sqlite) columnExists(ctx context.Context, column string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM pragma_table_info(?, ?)
+		WHERE name = ?
+	`, s.table, s.schemaName(), column).Scan(&count)
+
+	return count > 0, err
+}
+
+// execFunc runs a statement on either the database or a transaction.
+type execFunc func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
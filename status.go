@@ -0,0 +1,177 @@
+package migrator
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// MigrationState describes where a migration is in its lifecycle.
+type MigrationState string
+
+const (
+	// MigrationApplied is the state of a migration file that has been ran
+	// into the database.
+	MigrationApplied MigrationState = "applied"
+
+	// MigrationPending is the state of a migration file that has not yet
+	// been ran into the database.
+	MigrationPending MigrationState = "pending"
+
+	// MigrationOrphaned is the state of a migration that is recorded in the
+	// migration history table but has no file on disk.
+	MigrationOrphaned MigrationState = "orphaned"
+)
+
+// MigrationStatus is a representation of a single migration's state as
+// reported by Migrator.Status.
+type MigrationStatus struct {
+	// ID is the identifier of the migration.
+	ID int64 `json:"id"`
+
+	// FileName is the file name of the migration.
+	FileName string `json:"file_name"`
+
+	// State is whether the migration is applied, pending or orphaned.
+	State MigrationState `json:"state"`
+
+	// Ran is when the migration was ran into the database. It is nil for
+	// pending migrations.
+	Ran *time.Time `json:"ran,omitempty"`
+
+	// Duration is how long the migration took to run, to the millisecond.
+	// It, Host, User and Version are only set for migrations that were ran
+	// by a version of migrator that records them.
+	Duration time.Duration `json:"duration_ns,omitempty"`
+
+	// Host is the name of the machine that ran the migration.
+	Host string `json:"host,omitempty"`
+
+	// User is the name of the operating system user that ran the migration.
+	User string `json:"user,omitempty"`
+
+	// Version is the version of migrator that ran the migration.
+	Version string `json:"version,omitempty"`
+
+	// OutOfOrder is set for pending migrations with an ID lower than the
+	// highest applied migration, unless OutOfOrderAllow is configured.
+	OutOfOrder bool `json:"out_of_order,omitempty"`
+}
+
+// Status lists every migration, ordered by ID, along with whether it has been
+// applied, is pending or is recorded in the database without a file on disk.
+func (m Migrator) Status() ([]MigrationStatus, error) {
+	return m.StatusContext(context.Background())
+}
+
+// StatusContext lists every migration, ordered by ID, along with whether it
+// has been applied, is pending or is recorded in the database without a file
+// on disk. No transaction is opened and nothing is written to the database;
+// the history table is neither created nor upgraded, as that is only safe
+// while holding the migration lock.
+func (m Migrator) StatusContext(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if m.DatabaseServicer == nil {
+		return nil, ErrDbServicerNotInitialised
+	}
+
+	if err := m.configureHistoryTable(); err != nil {
+		return nil, err
+	}
+
+	migrationFiles, err := m.findMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := m.DatabaseServicer.HistoryTableExists(ctx)
+	if err != nil {
+		return nil, ErrUnableToRetrieveRanMigrations
+	}
+
+	// Without a history table nothing has been ran yet.
+	var ranMigrations []RanMigration
+	if exists {
+		if ranMigrations, err = m.DatabaseServicer.RanMigrations(ctx); err != nil {
+			return nil, ErrUnableToRetrieveRanMigrations
+		}
+	}
+
+	statuses := migrationStatuses(migrationFiles, ranMigrations)
+
+	if m.Config.OutOfOrderPolicy != OutOfOrderAllow {
+		pending, _ := pendingMigrations(migrationFiles, ranMigrations)
+		markOutOfOrder(statuses, outOfOrderMigrations(pending, ranMigrations))
+	}
+
+	return statuses, nil
+}
+
+func markOutOfOrder(statuses []MigrationStatus, outOfOrder []Migration) {
+	for _, migration := range outOfOrder {
+		for i := range statuses {
+			if statuses[i].FileName == migration.FileName {
+				statuses[i].OutOfOrder = true
+			}
+		}
+	}
+}
+
+func migrationStatuses(files []Migration, ran []RanMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(files))
+	matched := make(map[string]bool, len(ran))
+
+	for _, f := range files {
+		status := MigrationStatus{
+			ID:       f.ID,
+			FileName: f.FileName,
+			State:    MigrationPending,
+		}
+
+		for _, r := range ran {
+			if r.FileName == f.FileName {
+				status.State = MigrationApplied
+				status.setRan(r)
+				matched[r.FileName] = true
+				break
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, r := range ran {
+		if matched[r.FileName] {
+			continue
+		}
+
+		status := MigrationStatus{
+			ID:       r.ID,
+			FileName: r.FileName,
+			State:    MigrationOrphaned,
+		}
+		status.setRan(r)
+
+		statuses = append(statuses, status)
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+
+	return statuses
+}
+
+// setRan copies the details of when and how a migration was ran from its
+// migration history record.
+func (s *MigrationStatus) setRan(r RanMigration) {
+	ranAt := r.Ran
+	s.Ran = &ranAt
+	s.Duration = r.Duration
+	s.Host = r.Host
+	s.User = r.User
+	s.Version = r.Version
+}
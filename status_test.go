@@ -0,0 +1,136 @@
+package migrator_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+func TestStatusListsAppliedPendingAndOrphanedMigrations(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	os.Create(fmt.Sprintf("%s/3_third-migration_up.sql", config.MigrationsDir))
+	os.Create(fmt.Sprintf("%s/3_third-migration_down.sql", config.RollbacksDir))
+
+	ran := time.Now()
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return []migrator.RanMigration{
+			{
+				ID:       1,
+				FileName: "1_first-migration_up.sql",
+				Ran:      ran,
+			},
+			{
+				ID:       2,
+				FileName: "2_deleted-migration_up.sql",
+				Ran:      ran,
+			},
+		}, nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	expected := []struct {
+		id    int64
+		state migrator.MigrationState
+		ran   bool
+	}{
+		{1, migrator.MigrationApplied, true},
+		{2, migrator.MigrationOrphaned, true},
+		{3, migrator.MigrationPending, false},
+	}
+
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d statuses, got %d", len(expected), len(statuses))
+	}
+
+	for i, e := range expected {
+		s := statuses[i]
+		if s.ID != e.id || s.State != e.state || (s.Ran != nil) != e.ran {
+			t.Errorf("status %d was not correct: %+v", i, s)
+		}
+	}
+}
+
+func TestStatusDoesNotOpenATransaction(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	transactionCreated := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.BeginTransactionFunc = func() error {
+		transactionCreated = true
+		return nil
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if _, err := m.Status(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if transactionCreated {
+		t.Errorf("transaction was created when it shouldn't have been")
+	}
+}
+
+func TestStatusDoesNotCreateOrUpgradeTheHistoryTable(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	historyTableCreated := false
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.HistoryTableExistsFunc = func() (bool, error) {
+		return false, nil
+	}
+	db.TryCreateHistoryTableFunc = func() (bool, error) {
+		historyTableCreated = true
+		return true, nil
+	}
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return nil, fmt.Errorf("history table does not exist")
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if historyTableCreated {
+		t.Errorf("history table was created or upgraded outside of the migration lock")
+	}
+
+	for _, s := range statuses {
+		if s.State != migrator.MigrationPending {
+			t.Errorf("expected every migration to be pending, got %+v", s)
+		}
+	}
+}
+
+func TestErrorRetrievingRanMigrationsIsReturnedFromStatus(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	db := mock.WorkingMockDatabaseServicer()
+	db.RanMigrationsFunc = func() ([]migrator.RanMigration, error) {
+		return nil, fmt.Errorf("boo")
+	}
+
+	m := NewConfiguredMigrator(config, db, mock.MockLogServicer())
+	if _, err := m.Status(); err != migrator.ErrUnableToRetrieveRanMigrations {
+		t.Errorf("error returned was not correct")
+	}
+}
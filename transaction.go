@@ -0,0 +1,145 @@
+package migrator
+
+import "context"
+
+// TransactionMode is the strategy used to wrap migrations and rollbacks in
+// database transactions.
+type TransactionMode int
+
+const (
+	// TransactionModeDefault uses the default mode of the database servicer.
+	TransactionModeDefault TransactionMode = iota
+
+	// TransactionModeAll runs every migration of a run within a single
+	// transaction which is committed once they have all succeeded.
+	TransactionModeAll
+
+	// TransactionModePerMigration runs each migration, along with writing it
+	// to the migration history table, within a transaction of its own. Use
+	// this for engines such as MySQL that implicitly commit on DDL so that
+	// the history table stays consistent with the schema.
+	TransactionModePerMigration
+
+	// TransactionModeNone runs migrations without a transaction.
+	TransactionModeNone
+)
+
+// transactionMode resolves the configured transaction mode, falling back to
+// the database servicer's default.
+func (m Migrator) transactionMode() TransactionMode {
+	if m.Config.TransactionMode != TransactionModeDefault {
+		return m.Config.TransactionMode
+	}
+
+	if mode := m.DatabaseServicer.DefaultTransactionMode(); mode != TransactionModeDefault {
+		return mode
+	}
+
+	return TransactionModeAll
+}
+
+// batchTransactionMode is the mode used for multi-step rollbacks, which undo
+// every migration within a single transaction so that a failure part way
+// leaves none of them rolled back. Only TransactionModeNone is honoured.
+func (m Migrator) batchTransactionMode() TransactionMode {
+	if m.transactionMode() == TransactionModeNone {
+		return TransactionModeNone
+	}
+
+	return TransactionModeAll
+}
+
+// runSteps runs step for each of the migrations in order, wrapping them in
+// transactions as dictated by mode. Migrations for which outsideTransaction
+// returns true are always ran on their own; any batch transaction before them
+// is committed first so that the order is kept. phase is the phase reported
+// with each event that is logged.
+func (m Migrator) runSteps(ctx context.Context, phase string, mode TransactionMode, ms []Migration, outsideTransaction func(Migration) bool, step func(Migration) error) error {
+	var batch []Migration
+
+	runBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := m.runInTransaction(ctx, phase, batch, step)
+		batch = nil
+
+		return err
+	}
+
+	for _, migration := range ms {
+		switch {
+		case mode == TransactionModeNone || outsideTransaction(migration):
+			if err := runBatch(); err != nil {
+				return err
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if mode != TransactionModeNone {
+				m.logger().Info("running outside of a transaction",
+					LogKeyPhase, phase,
+					LogKeyMigrationID, migration.ID,
+					LogKeyFileName, migration.FileName)
+			}
+
+			if err := step(migration); err != nil {
+				return err
+			}
+
+			m.Hooks.afterCommit([]Migration{migration})
+		case mode == TransactionModePerMigration:
+			if err := m.runInTransaction(ctx, phase, []Migration{migration}, step); err != nil {
+				return err
+			}
+		default:
+			batch = append(batch, migration)
+		}
+	}
+
+	return runBatch()
+}
+
+// runInTransaction runs step for each of the migrations within a single
+// transaction, committing it only if every step succeeds.
+func (m Migrator) runInTransaction(ctx context.Context, phase string, ms []Migration, step func(Migration) error) error {
+	if err := m.DatabaseServicer.BeginTransaction(ctx); err != nil {
+		m.logger().Error("database error creating transaction",
+			LogKeyPhase, phase,
+			LogKeyError, err)
+		return ErrCreatingDbTransaction
+	}
+
+	m.logger().Debug("database transaction created", LogKeyPhase, phase)
+
+	defer m.DatabaseServicer.RollbackTransaction()
+
+	for _, migration := range ms {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := step(migration); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := m.DatabaseServicer.CommitTransaction(); err != nil {
+		m.logger().Error("database error committing transaction",
+			LogKeyPhase, phase,
+			LogKeyError, err)
+		return ErrCommittingTransaction
+	}
+
+	m.logger().Debug("committed database transaction", LogKeyPhase, phase)
+	m.Hooks.afterCommit(ms)
+
+	return nil
+}
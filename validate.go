@@ -0,0 +1,194 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// GapPolicy is how gaps in the sequence of migration IDs are treated.
+type GapPolicy int
+
+const (
+	// GapPolicyIgnore allows gaps between migration IDs. This is the
+	// default as timestamp IDs are never contiguous.
+	GapPolicyIgnore GapPolicy = iota
+
+	// GapPolicyWarn logs any gaps between migration IDs and carries on.
+	GapPolicyWarn
+
+	// GapPolicyError fails with ErrMigrationGaps when there are gaps
+	// between migration IDs.
+	GapPolicyError
+)
+
+// MigrationGap is a range of migration IDs, inclusive, that are missing from
+// an otherwise contiguous sequence.
+type MigrationGap struct {
+	From int64
+	To   int64
+}
+
+// String yields the missing ID, or range of IDs, for the MigrationGap struct.
+func (g MigrationGap) String() string {
+	if g.From == g.To {
+		return fmt.Sprintf("%d", g.From)
+	}
+
+	return fmt.Sprintf("%d-%d", g.From, g.To)
+}
+
+// RollbackLister is implemented by migration sources that keep rollback files
+// apart from their migrations, so that rollback files without a migration can
+// be reported.
+type RollbackLister interface {
+	// RollbackFiles returns the names of every rollback file.
+	RollbackFiles() ([]string, error)
+}
+
+// Validate checks the migrations without connecting to the database. It fails
+// when two migrations share an ID, when a rollback file has no migration or,
+// depending on Configuration.GapPolicy, when IDs are missing from the
+// sequence. The same checks are made before every run, except that rollback
+// files without a migration are only logged.
+func (m Migrator) Validate() error {
+	source := m.source()
+
+	found, err := discoverMigrations(source, m.logger())
+	if err != nil {
+		return err
+	}
+
+	return m.validateMigrations(source, found, true)
+}
+
+// validateMigrations is the validation stage ran after migrations have been
+// discovered. Orphaned rollback files are only an error when strict is set.
+func (m Migrator) validateMigrations(source MigrationSource, ms []Migration, strict bool) error {
+	if err := checkDuplicateIDs(ms); err != nil {
+		return err
+	}
+
+	if err := checkOrphanedRollbacks(source, ms); err != nil {
+		if _, ok := err.(ErrOrphanedRollbacks); !ok || strict {
+			return err
+		}
+
+		for _, file := range err.(ErrOrphanedRollbacks).Files {
+			m.logger().Warn("rollback file has no matching migration",
+				LogKeyPhase, phaseDiscover,
+				LogKeyFileName, file)
+		}
+	}
+
+	gaps := migrationGaps(ms)
+	if len(gaps) == 0 {
+		return nil
+	}
+
+	switch m.Config.GapPolicy {
+	case GapPolicyError:
+		return NewErrMigrationGaps(gaps)
+	case GapPolicyWarn:
+		for _, gap := range gaps {
+			m.logger().Warn("migration ids are missing from the sequence",
+				LogKeyPhase, phaseDiscover,
+				"missing_ids", gap.String())
+		}
+	}
+
+	return nil
+}
+
+// checkDuplicateIDs fails when more than one migration shares an ID, rather
+// than leaving the order they run in to chance.
+func checkDuplicateIDs(ms []Migration) error {
+	files := make(map[int64][]string, len(ms))
+	for _, m := range ms {
+		files[m.ID] = append(files[m.ID], m.FileName)
+	}
+
+	for _, m := range ms {
+		if len(files[m.ID]) > 1 {
+			return NewErrDuplicateMigrationID(m.ID, files[m.ID])
+		}
+	}
+
+	return nil
+}
+
+// checkOrphanedRollbacks fails when the source has rollback files that do not
+// belong to any migration, such as when an up file has been renamed.
+func checkOrphanedRollbacks(source MigrationSource, ms []Migration) error {
+	lister, ok := source.(RollbackLister)
+	if !ok {
+		return nil
+	}
+
+	rollbackFiles, err := lister.RollbackFiles()
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool, len(ms))
+	for _, m := range ms {
+		used[m.Rollback.FileName] = true
+	}
+
+	var orphaned []string
+	for _, r := range rollbackFiles {
+		if !used[r] {
+			orphaned = append(orphaned, r)
+		}
+	}
+
+	if len(orphaned) > 0 {
+		return NewErrOrphanedRollbacks(orphaned)
+	}
+
+	return nil
+}
+
+// migrationGaps returns the ranges of IDs missing between the lowest and
+// highest migration IDs.
+func migrationGaps(ms []Migration) []MigrationGap {
+	ids := make([]int64, 0, len(ms))
+	for _, m := range ms {
+		ids = append(ids, m.ID)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	var gaps []MigrationGap
+	for i := 1; i < len(ids); i++ {
+		if ids[i]-ids[i-1] > 1 {
+			gaps = append(gaps, MigrationGap{From: ids[i-1] + 1, To: ids[i] - 1})
+		}
+	}
+
+	return gaps
+}
+
+func (s fsSource) RollbackFiles() ([]string, error) {
+	rollbackFiles, err := fs.ReadDir(s.fsys, s.rollbacksDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Single-file migrations do not need a rollbacks directory.
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, NewErrSearchingDir(s.rollbacksDir, err)
+	}
+
+	var names []string
+	for _, r := range rollbackFiles {
+		if name, ok := parseFileName(r.Name()); ok && name.action == "down" {
+			names = append(names, r.Name())
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,116 @@
+package migrator_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bunsenapp/migrator"
+	"github.com/bunsenapp/migrator/mock"
+)
+
+func gappedConfiguration(policy migrator.GapPolicy) migrator.Configuration {
+	config := mock.ValidConfiguration()
+	config.GapPolicy = policy
+	config.FS = fstest.MapFS{
+		"my-migrations-directory/1_create-users_up.sql":  {},
+		"my-rollbacks-directory/1_create-users_down.sql": {},
+		"my-migrations-directory/4_create-posts_up.sql":  {},
+		"my-rollbacks-directory/4_create-posts_down.sql": {},
+	}
+
+	return config
+}
+
+func TestValidMigrationsPassValidation(t *testing.T) {
+	config, cleanUp := mock.ValidConfigurationDirectoriesAndFiles()
+	defer cleanUp()
+
+	m := NewConfiguredMigrator(config, nil, mock.MockLogServicer())
+	if err := m.Validate(); err != nil {
+		t.Errorf("error returned when it shouldn't have been: %s", err)
+	}
+}
+
+func TestRollbackWithoutAMigrationFailsValidation(t *testing.T) {
+	config := mock.ValidConfiguration()
+	config.FS = fstest.MapFS{
+		"my-migrations-directory/1_create-users_up.sql":  {},
+		"my-rollbacks-directory/1_create-users_down.sql": {},
+		"my-rollbacks-directory/2_create-posts_down.sql": {},
+	}
+
+	m := NewConfiguredMigrator(config, nil, mock.MockLogServicer())
+	err := m.Validate()
+
+	orphaned, ok := err.(migrator.ErrOrphanedRollbacks)
+	if !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if len(orphaned.Files) != 1 || orphaned.Files[0] != "2_create-posts_down.sql" {
+		t.Errorf("orphaned rollback was not reported: %v", orphaned.Files)
+	}
+}
+
+func TestRollbackWithoutAMigrationIsOnlyLoggedWhenMigrating(t *testing.T) {
+	config := mock.ValidConfiguration()
+	config.FS = fstest.MapFS{
+		"my-migrations-directory/1_create-users_up.sql":  {},
+		"my-rollbacks-directory/1_create-users_down.sql": {},
+		"my-rollbacks-directory/2_create-posts_down.sql": {},
+	}
+
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(config, mock.WorkingMockDatabaseServicer(), log.New(&buf, "", 0))
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if !strings.Contains(buf.String(), "2_create-posts_down.sql") {
+		t.Errorf("orphaned rollback was not logged: %s", buf.String())
+	}
+}
+
+func TestGapsFailValidationWhenConfigured(t *testing.T) {
+	m := NewConfiguredMigrator(gappedConfiguration(migrator.GapPolicyError), nil, mock.MockLogServicer())
+	err := m.Validate()
+
+	gaps, ok := err.(migrator.ErrMigrationGaps)
+	if !ok {
+		t.Fatalf("error returned was not correct: %v", err)
+	}
+
+	if len(gaps.Gaps) != 1 || gaps.Gaps[0].String() != "2-3" {
+		t.Errorf("gap was not reported correctly: %v", gaps.Gaps)
+	}
+}
+
+func TestGapsAreLoggedWhenConfigured(t *testing.T) {
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(gappedConfiguration(migrator.GapPolicyWarn), nil, log.New(&buf, "", 0))
+	if err := m.Validate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if !strings.Contains(buf.String(), "2-3") {
+		t.Errorf("gap was not logged: %s", buf.String())
+	}
+}
+
+func TestGapsAreIgnoredByDefault(t *testing.T) {
+	var buf bytes.Buffer
+
+	m := NewConfiguredMigrator(gappedConfiguration(migrator.GapPolicyIgnore), nil, log.New(&buf, "", 0))
+	if err := m.Validate(); err != nil {
+		t.Fatalf("error returned when it shouldn't have been: %s", err)
+	}
+
+	if strings.Contains(buf.String(), "2-3") {
+		t.Errorf("gap was logged when it should have been ignored: %s", buf.String())
+	}
+}